@@ -0,0 +1,964 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"src/main/main/idyck"
+)
+
+// newFlagSet returns a flag set for the command name whose usage lists the
+// positional arguments args
+func newFlagSet(name string, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: idyck %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args and checks the number of positional arguments.
+// The exit code is returned if the command should stop.
+func parseFlags(fs *flag.FlagSet, args []string, min int, max int) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	if fs.NArg() < min || fs.NArg() > max {
+		fs.Usage()
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// newContext is cancelled by an interrupt signal and after timeout, if
+// positive
+func newContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, "idyck:", err)
+	return exitError
+}
+
+// createOutput opens the file name for writing, "-" is stdout
+func createOutput(name string) (io.WriteCloser, error) {
+	if name == "-" || name == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	if dir := filepath.Dir(name); dir != "." {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return nil, err
+		}
+	}
+	return os.Create(name)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// openInput opens the file name for reading, "-" is stdin
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "-" || name == "" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// kindFlag is the benchmark kind, inferred from the directory of the graph
+// if not set
+type kindFlag struct {
+	kind idyck.Kind
+	set  bool
+}
+
+func (k *kindFlag) String() string {
+	if !k.set {
+		return ""
+	}
+	return k.kind.String()
+}
+
+func (k *kindFlag) Set(s string) error {
+	kind, err := idyck.ParseKind(s)
+	if err != nil {
+		return err
+	}
+	k.kind, k.set = kind, true
+	return nil
+}
+
+func (k *kindFlag) forGraph(graphFile string) idyck.Kind {
+	if k.set {
+		return k.kind
+	}
+	kind, err := idyck.ParseKind(filepath.Base(filepath.Dir(graphFile)))
+	if err != nil {
+		return idyck.Taint
+	}
+	return kind
+}
+
+// stagesFlag is a comma separated list of stages, nil if not set
+type stagesFlag struct {
+	stages []idyck.Stage
+}
+
+func (f *stagesFlag) String() string {
+	if f == nil || f.stages == nil {
+		return ""
+	}
+	names := []string{}
+	for _, stage := range f.stages {
+		names = append(names, string(stage))
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *stagesFlag) Set(s string) error {
+	stages, err := idyck.ParseStages(s)
+	if err != nil {
+		return err
+	}
+	f.stages = stages
+	return nil
+}
+
+// runConfig is the configuration file of the run command. Flags given on
+// the command line take precedence.
+type runConfig struct {
+	Kind     string        `json:"kind"`
+	ParityK  int           `json:"k"`
+	Stages   []idyck.Stage `json:"stages"`
+	Prune    []idyck.Stage `json:"prune"`
+	Workers  int           `json:"parallel"`
+	BottomUp bool          `json:"bottom_up"`
+}
+
+func readRunConfig(name string) (runConfig, error) {
+	config := runConfig{}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %v", name, err)
+	}
+	return config, nil
+}
+
+func writeResult(w io.Writer, res idyck.Result) error {
+	for i, stage := range res.Stages {
+		partial := ""
+		if res.Stats[i].Partial {
+			partial = " (partial)"
+		}
+		if _, err := fmt.Fprintf(w, "%s: %d%s\n", stage.Title(), len(res.Paths(stage)), partial); err != nil {
+			return err
+		}
+	}
+	if res.Interrupted != "" {
+		if _, err := fmt.Fprintf(w, "Interrupted: %s\n", res.Interrupted.Title()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// anchor is set by the -source and -sink flags of run and reach, negative
+// vertices are unset
+type anchor struct {
+	source int
+	sink   int
+}
+
+func (an *anchor) addFlags(fs *flag.FlagSet) {
+	fs.IntVar(&an.source, "source", -1, "only the pairs starting at this vertex, on the part of the graph it reaches (default: all pairs)")
+	fs.IntVar(&an.sink, "sink", -1, "only the pairs ending at this vertex, on the part of the graph that reaches it (default: all pairs)")
+}
+
+func (an *anchor) check() error {
+	if an.source >= 0 && an.sink >= 0 {
+		return fmt.Errorf("-source and -sink cannot be used together")
+	}
+	return nil
+}
+
+func (an *anchor) set() bool {
+	return an.source >= 0 || an.sink >= 0
+}
+
+// update is set by the -previous, -add, -remove and -write-graph flags of
+// run, see idyck.Analyzer.Update
+type update struct {
+	previous string //-pairs directory of the earlier run
+	add      string
+	remove   string
+	graphOut string
+}
+
+func (up *update) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&up.previous, "previous", "", "-pairs directory of a run on the graph, only the components changed by -add and -remove are analyzed again")
+	fs.StringVar(&up.add, "add", "", "graph file with the edges added since the -previous run")
+	fs.StringVar(&up.remove, "remove", "", "graph file with the edges removed since the -previous run")
+	fs.StringVar(&up.graphOut, "write-graph", "", "write the graph changed by -add and -remove to this file")
+}
+
+func (up *update) check(an anchor) error {
+	if up.previous == "" && (up.add != "" || up.remove != "" || up.graphOut != "") {
+		return fmt.Errorf("-add, -remove and -write-graph need -previous")
+	}
+	if up.previous != "" && an.set() {
+		return fmt.Errorf("-previous cannot be used with -source or -sink")
+	}
+	return nil
+}
+
+func (up *update) set() bool {
+	return up.previous != ""
+}
+
+// delta reads the edges of the -add and -remove graphs
+func (up *update) delta() (idyck.Delta, error) {
+	delta := idyck.Delta{}
+	for _, f := range []struct {
+		name  string
+		edges *[]idyck.Edge
+	}{{up.add, &delta.Added}, {up.remove, &delta.Removed}} {
+		if f.name == "" {
+			continue
+		}
+		g, err := idyck.ReadDotFile(f.name)
+		if err != nil {
+			return delta, err
+		}
+		for _, e := range g.GetEdges() {
+			if e.From != e.To || e.Label != "" {
+				*f.edges = append(*f.edges, e)
+			}
+		}
+	}
+	return delta, nil
+}
+
+// pairRun is written next to the pair files as <name>.run.json, so that
+// -previous can tell whether the earlier run matches
+type pairRun struct {
+	Stages      []idyck.Stage `json:"stages"`
+	Prune       []idyck.Stage `json:"prune"`
+	Interrupted idyck.Stage   `json:"interrupted,omitempty"`
+}
+
+// readPairFiles reads the result written by writePairFiles: the stages,
+// whether each pruned, the interrupted stage and the pairs of each stage
+func readPairFiles(dir string, name string) (idyck.Result, error) {
+	res := idyck.Result{}
+	runFile := filepath.Join(dir, name+".run.json")
+	data, err := os.ReadFile(runFile)
+	if err != nil {
+		return res, err
+	}
+	run := pairRun{}
+	if err := json.Unmarshal(data, &run); err != nil {
+		return res, fmt.Errorf("%s: %v", runFile, err)
+	}
+	pruned := map[idyck.Stage]bool{}
+	for _, stage := range run.Prune {
+		pruned[stage] = true
+	}
+	res.Interrupted = run.Interrupted
+	for _, stage := range run.Stages {
+		paths, err := idyck.ReadPathsFromFile(filepath.Join(dir, name+"."+string(stage)+".pairs"))
+		if err != nil {
+			return res, err
+		}
+		res.Stages = append(res.Stages, stage)
+		res.Stats = append(res.Stats, idyck.StageStats{Stage: stage, Pairs: len(paths), Pruned: pruned[stage]})
+		res.SetPaths(stage, paths)
+	}
+	return res, nil
+}
+
+// addSchemaFlag adds the -schema flag of the commands that read the Dyck
+// alphabet from the labels of the graph
+func addSchemaFlag(fs *flag.FlagSet) *string {
+	return fs.String("schema", "", "JSON file declaring the labels of the graph (default: op--N, cp--N, ob--N, cb--N and normal)")
+}
+
+func readSchema(name string) (*idyck.LabelSchema, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return idyck.ReadLabelSchemaFile(name)
+}
+
+// readGraph reads graphFile as it is and with its labels translated by
+// schema for the analyses, the same graph without schema
+func readGraph(graphFile string, schema *idyck.LabelSchema) (*idyck.Graph, *idyck.Graph, error) {
+	g, err := idyck.ReadDotFile(graphFile)
+	if err != nil || schema == nil {
+		return g, g, err
+	}
+	translated, err := schema.Apply(g)
+	if err != nil {
+		return g, nil, fmt.Errorf("%s: %v", graphFile, err)
+	}
+	return g, translated, nil
+}
+
+// outputs of the run command, empty names are not written
+type outputs struct {
+	text  string
+	json  string
+	csv   string
+	pairs string //directory for the pair and verdict files
+}
+
+func analyze(graphFile string, schema *idyck.LabelSchema, out outputs, config idyck.Config, an anchor, up update, timeout time.Duration) int {
+	read, g, err := readGraph(graphFile, schema)
+	if err != nil {
+		return fail(err)
+	}
+	a := idyck.NewAnalyzer(config)
+	var prev idyck.Result
+	var delta, analyzed idyck.Delta
+	if up.set() {
+		if prev, err = readPairFiles(up.previous, benchmarkName(graphFile)); err != nil {
+			return fail(err)
+		}
+		if delta, err = up.delta(); err != nil {
+			return fail(err)
+		}
+		analyzed = delta
+		if schema != nil {
+			if analyzed.Added, err = schema.ApplyEdges(delta.Added); err != nil {
+				return fail(err)
+			}
+			if analyzed.Removed, err = schema.ApplyEdges(delta.Removed); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "Running:", graphFile)
+	ctx, cancel := newContext(timeout)
+	defer cancel()
+	var res idyck.Result
+	var runErr error
+	switch {
+	case up.set():
+		_, res, runErr = a.Update(ctx, g, prev, analyzed)
+		if runErr != nil && ctx.Err() == nil {
+			return fail(runErr)
+		}
+		//the changed graph with the labels as read
+		next := delta.Apply(read)
+		if err := writeReportFile(up.graphOut, func(w io.Writer) error { return idyck.WriteDot(w, next) }); err != nil {
+			return fail(err)
+		}
+	case an.source >= 0:
+		res, runErr = a.RunFromSource(ctx, g, idyck.Vertex(an.source))
+	case an.sink >= 0:
+		res, runErr = a.RunToSink(ctx, g, idyck.Vertex(an.sink))
+	default:
+		res, runErr = a.RunContext(ctx, g)
+	}
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "idyck: %s interrupted: %v\n", res.Interrupted, runErr)
+	}
+
+	//with -source or -sink the verdict of every pair follows the counts
+	err = writeReportFile(out.text, func(w io.Writer) error {
+		if err := writeResult(w, res); err != nil || !an.set() {
+			return err
+		}
+		return idyck.WriteVerdicts(w, res.Verdicts())
+	})
+	if err != nil {
+		return fail(err)
+	}
+	r := makeReport(graphFile, a.Config(), res)
+	if err := writeReportFile(out.json, r.writeJSON); err != nil {
+		return fail(err)
+	}
+	if err := writeReportFile(out.csv, r.writeCSV); err != nil {
+		return fail(err)
+	}
+	if err := writePairFiles(out.pairs, benchmarkName(graphFile), res); err != nil {
+		return fail(err)
+	}
+	if runErr != nil {
+		return exitPartial
+	}
+	return exitOK
+}
+
+// writePairFiles writes the pairs of each stage to <dir>/<name>.<stage>.pairs,
+// the stages to <dir>/<name>.run.json (see pairRun) and the verdicts to
+// <dir>/<name>.verdicts, nothing if dir is empty
+func writePairFiles(dir string, name string, res idyck.Result) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	run := pairRun{Stages: res.Stages, Prune: []idyck.Stage{}, Interrupted: res.Interrupted}
+	for i, stage := range res.Stages {
+		fileName := filepath.Join(dir, name+"."+string(stage)+".pairs")
+		if err := idyck.WritePathsToFile(fileName, res.Paths(stage)); err != nil {
+			return err
+		}
+		if res.Stats[i].Pruned {
+			run.Prune = append(run.Prune, stage)
+		}
+	}
+	err := writeReportFile(filepath.Join(dir, name+".run.json"), func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(run)
+	})
+	if err != nil {
+		return err
+	}
+	verdicts := res.Verdicts()
+	return writeReportFile(filepath.Join(dir, name+".verdicts"), func(w io.Writer) error {
+		return idyck.WriteVerdicts(w, verdicts)
+	})
+}
+
+// legacyRun writes the result for <dir>/<graph>.dot to <dir>-out/<graph>.out
+// and the report to <dir>-out/<graph>.json
+func legacyRun(graphFile string) int {
+	dir := filepath.Clean(filepath.Dir(graphFile))
+	kind := (&kindFlag{}).forGraph(graphFile)
+	base := filepath.Join(dir+"-out", benchmarkName(graphFile))
+	return analyze(graphFile, nil, outputs{text: base + ".out", json: base + ".json"}, idyck.Config{Kind: kind}, anchor{-1, -1}, update{}, 0)
+}
+
+func runCommand(args []string) int {
+	fs := newFlagSet("run", "<graph.dot>")
+	kind := &kindFlag{}
+	fs.Var(kind, "kind", "benchmark kind, taint or valueflow (default: name of the directory of the graph, else taint)")
+	output := fs.String("o", "-", "output file, - for stdout")
+	parityK := fs.Int("k", 2, "k of the k-parity grammars used by the stronger grammar stages")
+	stages := &stagesFlag{}
+	fs.Var(stages, "stages", "comma separated stages to run in this order (default: all of "+stageNames(idyck.DefaultStages)+")")
+	prune := &stagesFlag{}
+	fs.Var(prune, "prune", "comma separated stages whose result prunes the graph (default: "+stageNames(idyck.DefaultPrune)+")")
+	workers := fs.Int("parallel", 0, "graph components analyzed concurrently (default: number of CPUs)")
+	bottomUp := fs.Bool("bottom-up", false, "refine single pairs with all pairs of the alpha and beta grammars instead of goal-directed")
+	configFile := fs.String("config", "", "JSON file with the fields kind, k, stages, prune, parallel and bottom_up")
+	jsonFile := fs.String("json", "", "also write a JSON report with pair counts, graph sizes and timings per stage, - for stdout")
+	csvFile := fs.String("csv", "", "also write the report as CSV, one row per stage, - for stdout")
+	pairsDir := fs.String("pairs", "", "also write the pairs of each stage and a verdict per pair to files in this directory")
+	timeout := fs.Duration("timeout", 0, "stop after this time (e.g. 10m) and write the results of the finished stages")
+	schemaFile := addSchemaFlag(fs)
+	an := anchor{}
+	an.addFlags(fs)
+	up := update{}
+	up.addFlags(fs)
+	if code, ok := parseFlags(fs, args, 1, 1); !ok {
+		return code
+	}
+	if err := an.check(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if err := up.check(an); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	graphFile := fs.Arg(0)
+	config := idyck.Config{Kind: kind.forGraph(graphFile), ParityK: *parityK}
+	if *configFile != "" {
+		file, err := readRunConfig(*configFile)
+		if err != nil {
+			return fail(err)
+		}
+		if file.Kind != "" && !kind.set {
+			if err := kind.Set(file.Kind); err != nil {
+				return fail(fmt.Errorf("%s: %v", *configFile, err))
+			}
+			config.Kind = kind.kind
+		}
+		config.Stages, config.Prune = file.Stages, file.Prune
+		if file.ParityK != 0 {
+			config.ParityK = file.ParityK
+		}
+		config.Parallelism = file.Workers
+		config.BottomUp = file.BottomUp
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "k":
+			config.ParityK = *parityK
+		case "stages":
+			config.Stages = stages.stages
+		case "prune":
+			config.Prune = prune.stages
+		case "parallel":
+			config.Parallelism = *workers
+		case "bottom-up":
+			config.BottomUp = *bottomUp
+		}
+	})
+
+	if config.ParityK < 1 {
+		fmt.Fprintln(os.Stderr, "-k must be positive")
+		return exitUsage
+	}
+	if err := config.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	schema, err := readSchema(*schemaFile)
+	if err != nil {
+		return fail(err)
+	}
+	return analyze(graphFile, schema, outputs{text: *output, json: *jsonFile, csv: *csvFile, pairs: *pairsDir}, config, an, up, *timeout)
+}
+
+func stageNames(stages []idyck.Stage) string {
+	return (&stagesFlag{stages}).String()
+}
+
+// grammar for the reach command: a .mcfg file, or one of the Dyck grammars
+// over the labels of the graph
+func reachGrammar(grammarFile string, dyck string, parityK int, edges []idyck.Edge) (idyck.MCFG, []idyck.Edge, error) {
+	if grammarFile != "" {
+		in, err := openInput(grammarFile)
+		if err != nil {
+			return idyck.MCFG{}, edges, err
+		}
+		defer in.Close()
+		m, err := idyck.ParseNormalForm(in)
+		return m, edges, err
+	}
+
+	labelsP, labelsB, edges := idyck.DyckEdges(edges)
+	var m idyck.MCFG
+	var err error
+	switch {
+	case dyck == "alpha" && parityK > 0:
+		m, err = idyck.DyckAlphaGrammarKParity(labelsP, labelsB, parityK)
+	case dyck == "beta" && parityK > 0:
+		m, err = idyck.DyckBetaGrammarKParity(labelsP, labelsB, parityK)
+	case dyck == "alpha":
+		m, err = idyck.DyckAlphaGrammar(labelsP, labelsB)
+	case dyck == "beta":
+		m, err = idyck.DyckBetaGrammar(labelsP, labelsB)
+	case dyck == "interleaved":
+		m, err = idyck.InterleavedDyckGrammar(labelsP, labelsB)
+	case dyck == "bracket":
+		m, err = idyck.BracketGrammar(labelsP, labelsB)
+	default:
+		err = fmt.Errorf("unknown Dyck grammar %q (alpha, beta, interleaved or bracket)", dyck)
+	}
+	return m, edges, err
+}
+
+// readEdges reads the edges of graphFile with their labels translated by
+// schema, without building the Graph
+func readEdges(graphFile string, schema *idyck.LabelSchema) ([]idyck.Edge, error) {
+	edges, err := idyck.ReadDotFileEdges(graphFile)
+	if err != nil || schema == nil {
+		return edges, err
+	}
+	if edges, err = schema.ApplyEdges(edges); err != nil {
+		return nil, fmt.Errorf("%s: %v", graphFile, err)
+	}
+	return edges, nil
+}
+
+func graphOf(edges []idyck.Edge) *idyck.Graph {
+	g := idyck.MakeGraph()
+	for _, e := range edges {
+		g.AddEdge(e.From, e.To, e.Label)
+	}
+	return g
+}
+
+func reachCommand(args []string) int {
+	fs := newFlagSet("reach", "<graph.dot>")
+	grammarFile := fs.String("grammar", "", "grammar in the .mcfg syntax, - for stdin (default: the -dyck grammar)")
+	dyck := fs.String("dyck", "interleaved", "Dyck grammar over the labels of the graph: alpha, beta, interleaved or bracket")
+	parityK := fs.Int("k", 0, "use the k-parity variant of the alpha and beta grammars")
+	output := fs.String("o", "-", "output file, - for stdout")
+	timeout := fs.Duration("timeout", 0, "stop after this time (e.g. 10m) and write the pairs found so far")
+	schemaFile := addSchemaFlag(fs)
+	an := anchor{}
+	an.addFlags(fs)
+	if code, ok := parseFlags(fs, args, 1, 1); !ok {
+		return code
+	}
+	if err := an.check(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if *schemaFile != "" && *grammarFile != "" {
+		fmt.Fprintln(os.Stderr, "-schema only applies to the -dyck grammars")
+		return exitUsage
+	}
+
+	schema, err := readSchema(*schemaFile)
+	if err != nil {
+		return fail(err)
+	}
+	edges, err := readEdges(fs.Arg(0), schema)
+	if err != nil {
+		return fail(err)
+	}
+	m, edges, err := reachGrammar(*grammarFile, *dyck, *parityK, edges)
+	if err != nil {
+		return fail(err)
+	}
+	ctx, cancel := newContext(*timeout)
+	defer cancel()
+	var paths []idyck.Path
+	var runErr error
+	switch {
+	case an.source >= 0:
+		paths, runErr = idyck.SingleSourceReachability(ctx, graphOf(edges), &m, idyck.Vertex(an.source))
+	case an.sink >= 0:
+		paths, runErr = idyck.SingleSinkReachability(ctx, graphOf(edges), &m, idyck.Vertex(an.sink))
+	default:
+		//all pairs run on the compact CSRGraph alone
+		grammar, err := idyck.CompileGrammar(&m)
+		if err != nil {
+			return fail(err)
+		}
+		paths, runErr = idyck.ReachCSR(ctx, idyck.NewCSRGraphFromEdges(edges), grammar)
+	}
+	if runErr != nil && ctx.Err() == nil {
+		return fail(runErr)
+	}
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, "idyck: interrupted:", runErr)
+	}
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return fail(err)
+	}
+	defer out.Close()
+	idyck.SortPaths(paths)
+	if err := idyck.WritePaths(out, paths); err != nil {
+		return fail(err)
+	}
+	if runErr != nil {
+		return exitPartial
+	}
+	return exitOK
+}
+
+// parsePair parses the vertices of a pair given on the command line
+func parsePair(from string, to string) (idyck.Path, error) {
+	start, err := strconv.Atoi(from)
+	if err != nil {
+		return idyck.Path{}, fmt.Errorf("vertex %q is not a number", from)
+	}
+	end, err := strconv.Atoi(to)
+	if err != nil {
+		return idyck.Path{}, fmt.Errorf("vertex %q is not a number", to)
+	}
+	return idyck.Path{Start: idyck.Vertex(start), End: idyck.Vertex(end)}, nil
+}
+
+func queryCommand(args []string) int {
+	fs := newFlagSet("query", "<graph.dot> <from> <to>")
+	kind := &kindFlag{}
+	fs.Var(kind, "kind", "benchmark kind, taint or valueflow (default: name of the directory of the graph, else taint)")
+	parityK := fs.Int("k", 2, "k of the k-parity grammars used by the stronger grammar stages")
+	stages := &stagesFlag{}
+	fs.Var(stages, "stages", "comma separated stages to run in this order (default: all of "+stageNames(idyck.DefaultStages)+")")
+	prune := &stagesFlag{}
+	fs.Var(prune, "prune", "comma separated stages whose result prunes the graph (default: "+stageNames(idyck.DefaultPrune)+")")
+	bottomUp := fs.Bool("bottom-up", false, "refine the pair with all pairs of the alpha and beta grammars instead of goal-directed")
+	output := fs.String("o", "-", "output file, - for stdout")
+	timeout := fs.Duration("timeout", 0, "stop after this time (e.g. 10m) and write the verdict so far")
+	schemaFile := addSchemaFlag(fs)
+	if code, ok := parseFlags(fs, args, 3, 3); !ok {
+		return code
+	}
+
+	pair, err := parsePair(fs.Arg(1), fs.Arg(2))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	config := idyck.Config{Kind: kind.forGraph(fs.Arg(0)), ParityK: *parityK, Stages: stages.stages, Prune: prune.stages, BottomUp: *bottomUp}
+	if config.ParityK < 1 {
+		fmt.Fprintln(os.Stderr, "-k must be positive")
+		return exitUsage
+	}
+	if err := config.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	schema, err := readSchema(*schemaFile)
+	if err != nil {
+		return fail(err)
+	}
+	_, g, err := readGraph(fs.Arg(0), schema)
+	if err != nil {
+		return fail(err)
+	}
+
+	ctx, cancel := newContext(*timeout)
+	defer cancel()
+	verdict, runErr := idyck.NewAnalyzer(config).Query(ctx, g, pair)
+	if runErr != nil && ctx.Err() == nil {
+		return fail(runErr)
+	}
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, "idyck: interrupted:", runErr)
+	}
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return fail(err)
+	}
+	defer out.Close()
+	if err := idyck.WriteVerdicts(out, []idyck.PairVerdict{verdict}); err != nil {
+		return fail(err)
+	}
+	if runErr != nil {
+		return exitPartial
+	}
+	return exitOK
+}
+
+func witnessCommand(args []string) int {
+	fs := newFlagSet("witness", "<graph.dot> <from> <to>")
+	kind := &kindFlag{}
+	fs.Var(kind, "kind", "benchmark kind, taint or valueflow (default: name of the directory of the graph, else taint)")
+	output := fs.String("o", "-", "output file, - for stdout")
+	timeout := fs.Duration("timeout", 0, "stop after this time (e.g. 10m)")
+	schemaFile := addSchemaFlag(fs)
+	if code, ok := parseFlags(fs, args, 3, 3); !ok {
+		return code
+	}
+
+	pair, err := parsePair(fs.Arg(1), fs.Arg(2))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	schema, err := readSchema(*schemaFile)
+	if err != nil {
+		return fail(err)
+	}
+	read, g, err := readGraph(fs.Arg(0), schema)
+	if err != nil {
+		return fail(err)
+	}
+
+	ctx, cancel := newContext(*timeout)
+	defer cancel()
+	a := idyck.NewAnalyzer(idyck.Config{Kind: kind.forGraph(fs.Arg(0))})
+	edges, err := a.Witness(ctx, g, pair)
+	if err != nil {
+		return fail(err)
+	}
+	if schema != nil {
+		edges = schema.Restore(read, edges)
+	}
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return fail(err)
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	for _, e := range edges {
+		fmt.Fprintf(w, "%d %d %s\n", e.From, e.To, e.Label)
+	}
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func deriveCommand(args []string) int {
+	fs := newFlagSet("derive", "<graph.dot> <from> <to>")
+	grammarFile := fs.String("grammar", "", "grammar in the .mcfg syntax, - for stdin (default: the -dyck grammar)")
+	dyck := fs.String("dyck", "interleaved", "Dyck grammar over the labels of the graph: alpha, beta, interleaved or bracket")
+	parityK := fs.Int("k", 0, "use the k-parity variant of the alpha and beta grammars")
+	format := fs.String("format", "dot", "output format, dot or json")
+	output := fs.String("o", "-", "output file, - for stdout")
+	timeout := fs.Duration("timeout", 0, "stop after this time (e.g. 10m)")
+	schemaFile := addSchemaFlag(fs)
+	if code, ok := parseFlags(fs, args, 3, 3); !ok {
+		return code
+	}
+	if *schemaFile != "" && *grammarFile != "" {
+		fmt.Fprintln(os.Stderr, "-schema only applies to the -dyck grammars")
+		return exitUsage
+	}
+	if *format != "dot" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format %q (dot or json)\n", *format)
+		return exitUsage
+	}
+	pair, err := parsePair(fs.Arg(1), fs.Arg(2))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	schema, err := readSchema(*schemaFile)
+	if err != nil {
+		return fail(err)
+	}
+	edges, err := readEdges(fs.Arg(0), schema)
+	if err != nil {
+		return fail(err)
+	}
+	m, edges, err := reachGrammar(*grammarFile, *dyck, *parityK, edges)
+	if err != nil {
+		return fail(err)
+	}
+	g := graphOf(edges)
+	ctx, cancel := newContext(*timeout)
+	defer cancel()
+	dag, err := idyck.DerivationDAGOf(ctx, g, &m, pair)
+	if err != nil {
+		return fail(err)
+	}
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return fail(err)
+	}
+	defer out.Close()
+	if *format == "json" {
+		err = dag.WriteJSON(out)
+	} else {
+		err = dag.WriteDOT(out)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func grammarCommand(args []string) int {
+	fs := newFlagSet("grammar", "[grammar.mcfg | -]")
+	rank := fs.Bool("rank", false, "transform to rank at most 2")
+	perm := fs.Bool("perm", false, "transform to a non-deleting, non-permuting grammar")
+	norm := fs.Bool("norm", false, "transform to the normal form used by the engine")
+	output := fs.String("o", "-", "output file, - for stdout")
+	if code, ok := parseFlags(fs, args, 0, 1); !ok {
+		return code
+	}
+
+	in, err := openInput(fs.Arg(0))
+	if err != nil {
+		return fail(err)
+	}
+	defer in.Close()
+	ast, err := idyck.ParseMCFG(in)
+	if err != nil {
+		return fail(err)
+	}
+	for _, v := range ast.Check() {
+		fmt.Fprintln(os.Stderr, v)
+	}
+	if err := ast.Validate(); err != nil {
+		return exitError
+	}
+
+	if *rank {
+		if ast, err = ast.ReduceRank(); err != nil {
+			return fail(err)
+		}
+	}
+	if *perm {
+		if ast, err = ast.NonPermuting(); err != nil {
+			return fail(err)
+		}
+	}
+	text := ast.String()
+	if *norm {
+		m, err := ast.MCFG()
+		if err != nil {
+			return fail(err)
+		}
+		text = m.String()
+	}
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return fail(err)
+	}
+	defer out.Close()
+	if _, err := fmt.Fprintln(out, text); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func statsCommand(args []string) int {
+	fs := newFlagSet("stats", "<graph.dot>")
+	output := fs.String("o", "-", "output file, - for stdout")
+	schemaFile := addSchemaFlag(fs)
+	if code, ok := parseFlags(fs, args, 1, 1); !ok {
+		return code
+	}
+
+	schema, err := readSchema(*schemaFile)
+	if err != nil {
+		return fail(err)
+	}
+	g, translated, err := readGraph(fs.Arg(0), schema)
+	if err != nil {
+		return fail(err)
+	}
+
+	labels := map[idyck.Label]bool{}
+	for _, e := range g.GetEdges() {
+		if e.Label == "" { //epsilon self-loops added for every vertex
+			continue
+		}
+		labels[e.Label] = true
+	}
+	labelsP, labelsB, dyck := idyck.ParseDyckComponent(translated)
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return fail(err)
+	}
+	defer out.Close()
+	_, err = fmt.Fprintf(out, "Vertices: %d\nEdges: %d\nLabels: %d\nParentheses: %d\nBrackets: %d\nDyck vertices: %d\nDyck edges: %d\n",
+		g.NumVertices(), g.NumEdges(), len(labels), len(labelsP), len(labelsB), dyck.NumVertices(), dyck.NumEdges())
+	if err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
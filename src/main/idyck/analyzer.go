@@ -0,0 +1,161 @@
+package idyck
+
+import (
+	"context"
+)
+
+// Config holds the parameters of an analysis.
+type Config struct {
+	Kind    Kind
+	ParityK int //k used by the k-parity (augmented) grammars, 2 if unset
+
+	Stages []Stage //stages run by Run in this order, DefaultStages if nil
+	Prune  []Stage //stages whose result prunes the graph, DefaultPrune if nil
+
+	//graph components analyzed concurrently, runtime.GOMAXPROCS(0) if 0
+	Parallelism int
+
+	//refine single pairs with all pairs of the alpha/beta grammars instead
+	//of goal-directed, see newGoalReach
+	BottomUp bool
+}
+
+// GrammarProfile selects the alpha/beta grammars used by mutual refinement.
+type GrammarProfile string
+
+const (
+	Classic   GrammarProfile = "classic"
+	Augmented GrammarProfile = "augmented" //k-parity grammars
+)
+
+// Analyzer owns the state of a single analysis: its configuration, the
+// grammar currently used by mutual refinement, the provenance recorded by
+// the last reachability call and the alpha/beta memo tables.
+// An Analyzer is not safe for concurrent use, but independent Analyzers
+// can run concurrently. The stages analyze independent components of the
+// graph and the on-demand pairs on forks of the Analyzer, see forEach.
+type Analyzer struct {
+	kind    Kind
+	grammar GrammarProfile
+	parityK int
+	stages  []Stage
+	prune   map[Stage]bool
+	workers int
+
+	recordEdge bool
+	prov       *provenance
+	bottomUp   bool
+
+	//measurements of the current stage, see Run
+	reachStats []ReachStats
+	mem        *memSampler
+
+	queries int //on-demand refinements since the memo tables were cleared
+
+	anchor *anchor //pairs derived by RunFromSource and RunToSink, nil for all pairs
+
+	kept map[Path]bool //pairs that on-demand refinement keeps without refining, see Update
+
+	grammars map[string]*CompiledGrammar //see getAlphaGrammar
+
+	graphIDs map[string]int32 //edge sets of the memoized graphs, see newMemoKey
+
+	alphaSeenMap  map[memoKey]bool
+	alphaProvMap  map[memoKey]*provenance
+	alphaPathsMap map[memoKey][]Path
+
+	betaSeenMap  map[memoKey]bool
+	betaProvMap  map[memoKey]*provenance
+	betaPathsMap map[memoKey][]Path
+}
+
+// memoKey identifies a run of the alpha or beta grammar: the graph, by the
+// id of its edge set in graphIDs, and the pair of a goal-directed run
+type memoKey struct {
+	graph    int32
+	goal     Path
+	directed bool
+}
+
+func NewAnalyzer(config Config) *Analyzer {
+	a := &Analyzer{
+		kind:       config.Kind,
+		grammar:    Classic,
+		parityK:    config.ParityK,
+		workers:    config.Parallelism,
+		recordEdge: true,
+		bottomUp:   config.BottomUp,
+		grammars:   map[string]*CompiledGrammar{},
+	}
+	if a.parityK <= 0 {
+		a.parityK = 2
+	}
+	a.stages = config.Stages
+	if a.stages == nil {
+		a.stages = DefaultStages
+	}
+	prune := config.Prune
+	if prune == nil {
+		prune = DefaultPrune
+	}
+	a.prune = make(map[Stage]bool)
+	for _, stage := range prune {
+		a.prune[stage] = true
+	}
+	a.clearMaps()
+	return a
+}
+
+func (a *Analyzer) Config() Config {
+	prune := []Stage{}
+	for _, stage := range a.stages {
+		if a.prune[stage] {
+			prune = append(prune, stage)
+		}
+	}
+	return Config{
+		Kind:    a.kind,
+		ParityK: a.parityK,
+		Stages:  append([]Stage{}, a.stages...),
+		Prune:   prune,
+
+		Parallelism: a.parallelism(),
+		BottomUp:    a.bottomUp,
+	}
+}
+
+// SetGrammar selects the grammars used by MutualRefinement, MROverApprox
+// and OnDemandMR. The alpha/beta memo tables are cleared.
+func (a *Analyzer) SetGrammar(profile GrammarProfile) {
+	a.clearMaps()
+	a.grammar = profile
+}
+
+func (a *Analyzer) valueflow() bool {
+	return a.kind == Valueflow
+}
+
+// allPairsReachability runs AllPairsReachability with a compiled grammar
+// and keeps the provenance of the derivations (if a.recordEdge) for
+// usedEdges and filterUsedEdges. With a goal only the derivations that a
+// derivation of S(goal) can use are made, see newGoalReach; else with an
+// anchor only the pairs it keeps are derived, see newAnchoredReach.
+func (a *Analyzer) allPairsReachability(ctx context.Context, g *Graph, grammar *CompiledGrammar, goal *Path) ([]Path, error) {
+	var reachData *reach
+	if goal != nil {
+		reachData = newGoalReach(g, grammar, a.recordEdge, *goal)
+	} else if a.anchor != nil {
+		reachData = newAnchoredReach(g, grammar, a.recordEdge, a.anchor)
+	} else {
+		reachData = newReach(g, grammar, a.recordEdge)
+	}
+	reachData.sample = a.sampleMem
+	paths, err := reachData.run(ctx)
+	if goal == nil {
+		paths = a.anchored(paths)
+	}
+	a.prov = reachData.prov
+	a.sampleMem()
+	a.reachStats = append(a.reachStats, reachData.stats)
+	return paths, err
+}
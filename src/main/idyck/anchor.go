@@ -0,0 +1,171 @@
+package idyck
+
+import (
+	"context"
+)
+
+// slice returns the vertices reachable from v following next, v included
+// if it is a vertex of g
+func (g *Graph) slice(v Vertex, next func(Vertex) VertexList) map[Vertex]bool {
+	seen := map[Vertex]bool{}
+	if !g.vertices[v] {
+		return seen
+	}
+	seen[v] = true
+	stack := []Vertex{v}
+	for len(stack) > 0 {
+		u := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, w := range next(u) {
+			if !seen[w] {
+				seen[w] = true
+				stack = append(stack, w)
+			}
+		}
+	}
+	return seen
+}
+
+// forwardSlice keeps the edges reachable from source, the only ones on a
+// path from source, and source itself
+func (g *Graph) forwardSlice(source Vertex) *Graph {
+	reached := g.slice(source, g.OutEdgesUnlabeled)
+	slice := MakeGraph()
+	if reached[source] {
+		slice.addVertex(source)
+	}
+	for _, e := range g.edgeList {
+		if reached[e.From] && (e.From != e.To || len(e.Label) > 0) {
+			slice.AddEdge(e.From, e.To, e.Label)
+		}
+	}
+	return slice
+}
+
+// backwardSlice keeps the edges that reach sink, the only ones on a path
+// to sink, and sink itself
+func (g *Graph) backwardSlice(sink Vertex) *Graph {
+	reaching := g.slice(sink, g.InEdgesUnlabeled)
+	slice := MakeGraph()
+	if reaching[sink] {
+		slice.addVertex(sink)
+	}
+	for _, e := range g.edgeList {
+		if reaching[e.To] && (e.From != e.To || len(e.Label) > 0) {
+			slice.AddEdge(e.From, e.To, e.Label)
+		}
+	}
+	return slice
+}
+
+func filterPaths(paths []Path, keep func(Path) bool) []Path {
+	res := []Path{}
+	for _, p := range paths {
+		if keep(p) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// anchor restricts a run to the pairs that start at one of vertices, or
+// end at one of them if sink. Stages that transform the graph map the
+// anchor to the vertices of their graph, see mapped.
+type anchor struct {
+	vertices []Vertex
+	sink     bool
+}
+
+func (an *anchor) keeps(p Path) bool {
+	v := p.Start
+	if an.sink {
+		v = p.End
+	}
+	for _, u := range an.vertices {
+		if u == v {
+			return true
+		}
+	}
+	return false
+}
+
+// mapped returns the anchor on the vertices that f gives for the vertices
+// of an, nil for nil
+func (an *anchor) mapped(f func(Vertex) []Vertex) *anchor {
+	if an == nil {
+		return nil
+	}
+	res := &anchor{vertices: []Vertex{}, sink: an.sink}
+	for _, v := range an.vertices {
+		res.vertices = append(res.vertices, f(v)...)
+	}
+	return res
+}
+
+// anchored returns the paths kept by the anchor of a
+func (a *Analyzer) anchored(paths []Path) []Path {
+	if a.anchor == nil || paths == nil {
+		return paths
+	}
+	return filterPaths(paths, a.anchor.keeps)
+}
+
+// withAnchor sets the anchor of a until the returned function is called
+func (a *Analyzer) withAnchor(an *anchor) func() {
+	prev := a.anchor
+	a.anchor = an
+	return func() { a.anchor = prev }
+}
+
+// anchoredReachability is AllPairsReachability for the pairs kept by an,
+// seeded with the demand for S at its vertices
+func anchoredReachability(ctx context.Context, g *Graph, m *MCFG, an *anchor) ([]Path, error) {
+	grammar, err := CompileGrammar(m)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := newAnchoredReach(g, grammar, false, an).run(ctx)
+	return filterPaths(paths, an.keeps), err
+}
+
+// SingleSourceReachability is AllPairsReachability for the pairs starting
+// at source. It runs on the forward slice of source only and derives only
+// what a derivation of S from source can use.
+func SingleSourceReachability(ctx context.Context, g *Graph, m *MCFG, source Vertex) ([]Path, error) {
+	return anchoredReachability(ctx, g.forwardSlice(source), m, &anchor{vertices: []Vertex{source}})
+}
+
+// SingleSinkReachability is AllPairsReachability for the pairs ending at
+// sink. It runs on the backward slice of sink only and derives only what a
+// derivation of S to sink can use.
+func SingleSinkReachability(ctx context.Context, g *Graph, m *MCFG, sink Vertex) ([]Path, error) {
+	return anchoredReachability(ctx, g.backwardSlice(sink), m, &anchor{vertices: []Vertex{sink}, sink: true})
+}
+
+// RunFromSource is RunContext for the pairs starting at source: the stages
+// run on the forward slice of source and derive only pairs from source,
+// which also prune the graph and are the only ones refined on demand. The
+// under-approximation is the exception, it condenses the graph for the
+// later stages and takes all pairs of the slice. Result.Verdicts gives the
+// verdict of each pair, the vertices that are in no stage are not
+// reachable from source. The size of the input graph in the result is the
+// one of g.
+func (a *Analyzer) RunFromSource(ctx context.Context, g *Graph, source Vertex) (Result, error) {
+	return a.runAnchored(ctx, g, g.forwardSlice(source), &anchor{vertices: []Vertex{source}})
+}
+
+// RunToSink is RunFromSource for the pairs ending at sink, on the backward
+// slice of sink.
+func (a *Analyzer) RunToSink(ctx context.Context, g *Graph, sink Vertex) (Result, error) {
+	return a.runAnchored(ctx, g, g.backwardSlice(sink), &anchor{vertices: []Vertex{sink}, sink: true})
+}
+
+func (a *Analyzer) runAnchored(ctx context.Context, g *Graph, slice *Graph, an *anchor) (Result, error) {
+	//the memo tables of unanchored runs do not apply
+	a.clearMaps()
+	defer a.clearMaps()
+	defer a.withAnchor(an)()
+	res, err := a.RunContext(ctx, slice)
+	res.Vertices, res.Edges = g.NumVertices(), g.NumEdges()
+	return res, err
+}
@@ -0,0 +1,114 @@
+package idyck
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+var _dyckTestLabels = []Label{"op--0", "cp--0", "op--1", "cp--1", "ob--0", "cb--0", "normal"}
+
+// dyckGrammars returns the alpha, beta and 2-parity grammars and the
+// interleaved Dyck grammar for the labels of g, with g as ParseDyckComponent
+// leaves it
+func dyckGrammars(t *testing.T, g *Graph) (map[string]*MCFG, *Graph) {
+	labelsP, labelsB, g := ParseDyckComponent(g)
+	grammars := map[string]*MCFG{}
+	for name, make := range map[string]func() (MCFG, error){
+		"alpha":        func() (MCFG, error) { return DyckAlphaGrammar(labelsP, labelsB) },
+		"beta":         func() (MCFG, error) { return DyckBetaGrammar(labelsP, labelsB) },
+		"alpha-parity": func() (MCFG, error) { return DyckAlphaGrammarKParity(labelsP, labelsB, 2) },
+		"beta-parity":  func() (MCFG, error) { return DyckBetaGrammarKParity(labelsP, labelsB, 2) },
+		"interleaved":  func() (MCFG, error) { return InterleavedDyckGrammar(labelsP, labelsB) },
+	} {
+		m, err := make()
+		if err != nil {
+			t.Fatal(err)
+		}
+		grammars[name] = &m
+	}
+	return grammars, g
+}
+
+// TestAnchoredReach checks that the anchored reach finds the pairs of all
+// pairs reachability at its vertex, and that it derives less
+func TestAnchoredReach(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	anchored, full := 0, 0
+	for i := 0; i < 10; i++ {
+		grammars, g := dyckGrammars(t, randomGraph(rng, 6, 16, _dyckTestLabels))
+		for name, m := range grammars {
+			grammar, err := CompileGrammar(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := newReach(g, grammar, false)
+			all, err := r.run(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			derivations := r.stats.Derivations
+			for _, v := range g.Vertices() {
+				for _, an := range []*anchor{{vertices: []Vertex{v}}, {vertices: []Vertex{v}, sink: true}} {
+					r := newAnchoredReach(g, grammar, false, an)
+					paths, err := r.run(ctx)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got, want := filterPaths(paths, an.keeps), filterPaths(all, an.keeps)
+					SortPaths(got)
+					SortPaths(want)
+					if fmt.Sprint(got) != fmt.Sprint(want) {
+						t.Fatalf("graph %d, %s, anchor %v: got %v, want %v", i, name, *an, got, want)
+					}
+					full += derivations
+					anchored += r.stats.Derivations
+				}
+			}
+		}
+	}
+	if anchored >= full {
+		t.Errorf("anchored runs made %d derivations, full runs %d", anchored, full)
+	}
+}
+
+func TestSingleSourceAndSink(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 20; i++ {
+		g := randomGraph(rng, 8, 24, _dyckTestLabels)
+		labelsP, labelsB, g := ParseDyckComponent(g)
+		m, err := InterleavedDyckGrammar(labelsP, labelsB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all, _, err := AllPairsReachability(ctx, g, &m, false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range g.Vertices() {
+			source, err := SingleSourceReachability(ctx, g, &m, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sink, err := SingleSinkReachability(ctx, g, &m, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := filterPaths(all, func(p Path) bool { return p.Start == v })
+			SortPaths(source)
+			SortPaths(want)
+			if fmt.Sprint(source) != fmt.Sprint(want) {
+				t.Errorf("graph %d, source %d: got %v, want %v", i, v, source, want)
+			}
+			want = filterPaths(all, func(p Path) bool { return p.End == v })
+			SortPaths(sink)
+			SortPaths(want)
+			if fmt.Sprint(sink) != fmt.Sprint(want) {
+				t.Errorf("graph %d, sink %d: got %v, want %v", i, v, sink, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package idyck
+
+// the start nonterminal is number 0 in every CompiledGrammar
+const _startID int32 = 0
+
+// CompiledGrammar is an MCFG prepared for the reachability engine: the
+// nonterminals are numbered, the basic rules are indexed by their label and
+// the other rules by the nonterminals of their bodies. It does not change
+// after CompileGrammar, so one CompiledGrammar can be used by any number of
+// reachability computations, also concurrently.
+type CompiledGrammar struct {
+	names []string //nonterminal id -> name
+	ids   map[string]int32
+
+	basic map[Label][]int32 //label -> heads of the basic rules
+
+	//by the id of the body nonterminal
+	prepend [][]prependRule
+	append  [][]appendRule
+	insert  [][]insertRule
+	concat  [][]concatUse
+
+	dimension int
+	demand    [3]*demandPlan //for goal-directed evaluation, by goalKind
+}
+
+type prependRule struct {
+	PrependRule
+	head int32
+}
+
+type appendRule struct {
+	AppendRule
+	head int32
+}
+
+type insertRule struct {
+	InsertRule
+	head int32
+}
+
+type concatRule struct {
+	ConcatenateRule
+	head   int32
+	bodies []int32
+	sip    [][]sipLink //by body, see sipLink
+}
+
+// concatUse is an occurrence of a nonterminal in the body of a rule
+type concatUse struct {
+	rule *concatRule
+	idx  int
+}
+
+// CompileGrammar validates m and compiles it.
+func CompileGrammar(m *MCFG) (*CompiledGrammar, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return compileGrammar(m), nil
+}
+
+// compileGrammar compiles m without validating it, for the grammars built
+// by the package
+func compileGrammar(m *MCFG) *CompiledGrammar {
+	c := &CompiledGrammar{
+		names:     []string{},
+		ids:       map[string]int32{},
+		basic:     map[Label][]int32{},
+		dimension: m.Dimension(),
+	}
+	c.id(_startNonTerminal)
+
+	for _, rule := range m.BasicRules {
+		c.basic[rule.Label] = append(c.basic[rule.Label], c.id(rule.HeadName))
+	}
+	for _, rule := range m.PrependRules {
+		head, body := c.id(rule.HeadName), c.id(rule.BodyName)
+		c.prepend[body] = append(c.prepend[body], prependRule{rule, head})
+	}
+	for _, rule := range m.AppendRules {
+		head, body := c.id(rule.HeadName), c.id(rule.BodyName)
+		c.append[body] = append(c.append[body], appendRule{rule, head})
+	}
+	for _, rule := range m.InsertRules {
+		head, body := c.id(rule.HeadName), c.id(rule.BodyName)
+		c.insert[body] = append(c.insert[body], insertRule{rule, head})
+	}
+	for _, rule := range m.ConcatenateRules {
+		compiled := &concatRule{ConcatenateRule: rule, head: c.id(rule.HeadName)}
+		for i, name := range rule.BodyNames {
+			body := c.id(name)
+			compiled.bodies = append(compiled.bodies, body)
+			c.concat[body] = append(c.concat[body], concatUse{compiled, i})
+		}
+	}
+	c.demand = newDemandPlans(c)
+	return c
+}
+
+// id returns the number of the nonterminal name, adding it if it is new
+func (c *CompiledGrammar) id(name string) int32 {
+	if id, ok := c.ids[name]; ok {
+		return id
+	}
+	id := int32(len(c.names))
+	c.names = append(c.names, name)
+	c.ids[name] = id
+	c.prepend = append(c.prepend, nil)
+	c.append = append(c.append, nil)
+	c.insert = append(c.insert, nil)
+	c.concat = append(c.concat, nil)
+	return id
+}
+
+// NumNonterminals returns the number of nonterminals, including the start
+// nonterminal S even if m has no rule for it.
+func (c *CompiledGrammar) NumNonterminals() int {
+	return len(c.names)
+}
+
+// Dimension is the dimension of the compiled MCFG.
+func (c *CompiledGrammar) Dimension() int {
+	return c.dimension
+}
+
+// name returns the name of the nonterminal id
+func (c *CompiledGrammar) name(id int32) string {
+	return c.names[id]
+}
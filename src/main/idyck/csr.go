@@ -0,0 +1,227 @@
+package idyck
+
+import (
+	"sort"
+)
+
+// CSRGraph is an immutable, compact form of a Graph used by the
+// reachability engine and the SCC code. Vertices and labels are numbered
+// densely (in increasing order) and the adjacency is stored in compressed
+// sparse row form, once by source and once by target, with the row of
+// every vertex sorted by label. Build it with NewCSRGraph.
+type CSRGraph struct {
+	vertices []Vertex //dense id -> vertex
+	ids      map[Vertex]int32
+	labels   []Label //dense id -> label
+	labelIDs map[Label]int32
+
+	out csr
+	in  csr
+
+	//edges sorted by label, the edges of label l are
+	//edges[labelStart[l]:labelStart[l+1]]
+	edges      []Edge
+	labelStart []int32
+	epsilon    int //number of epsilon self-loops
+}
+
+// csr stores the neighbours of vertex v, sorted by label, in
+// adj[offsets[v]:offsets[v+1]] with their labels in label
+type csr struct {
+	offsets []int32
+	adj     []int32
+	label   []int32
+}
+
+// NewCSRGraph converts g. Later changes to g do not affect the result.
+func NewCSRGraph(g *Graph) *CSRGraph {
+	edges := make([]Edge, 0, len(g.edgeList))
+	labels := make([]Label, 0, len(g.labelToEdges))
+	for label := range g.labelToEdges {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i] < labels[j] })
+	for _, label := range labels {
+		edges = append(edges, g.labelToEdges[label]...)
+	}
+	vertices := make([]Vertex, 0, len(g.vertices))
+	for v := range g.vertices {
+		vertices = append(vertices, v)
+	}
+	sort.Slice(vertices, func(i, j int) bool { return vertices[i] < vertices[j] })
+	return newCSRGraph(vertices, edges)
+}
+
+// NewCSRGraphFromEdges returns the CSRGraph of the graph with edges, as
+// NewCSRGraph of a Graph built with AddEdge but without building it:
+// every vertex gets an epsilon self-loop and duplicate edges are dropped.
+func NewCSRGraphFromEdges(edges []Edge) *CSRGraph {
+	seen := map[Vertex]bool{}
+	all := make([]Edge, 0, len(edges))
+	for _, e := range edges {
+		all = append(all, e)
+		for _, v := range []Vertex{e.From, e.To} {
+			if !seen[v] {
+				seen[v] = true
+				all = append(all, Edge{From: v, To: v, Label: _epsilonLabel})
+			}
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	unique := all[:0]
+	for i, e := range all {
+		if i == 0 || e != all[i-1] {
+			unique = append(unique, e)
+		}
+	}
+	vertices := make([]Vertex, 0, len(seen))
+	for v := range seen {
+		vertices = append(vertices, v)
+	}
+	sort.Slice(vertices, func(i, j int) bool { return vertices[i] < vertices[j] })
+	return newCSRGraph(vertices, unique)
+}
+
+// newCSRGraph builds the graph with the sorted vertices and the edges
+// sorted by label
+func newCSRGraph(vertices []Vertex, edges []Edge) *CSRGraph {
+	c := &CSRGraph{
+		vertices: vertices,
+		ids:      make(map[Vertex]int32, len(vertices)),
+		labelIDs: map[Label]int32{},
+		edges:    edges,
+	}
+	for i, v := range c.vertices {
+		c.ids[v] = int32(i)
+	}
+	for i, e := range c.edges {
+		if i == 0 || e.Label != c.edges[i-1].Label {
+			c.labelIDs[e.Label] = int32(len(c.labels))
+			c.labels = append(c.labels, e.Label)
+			c.labelStart = append(c.labelStart, int32(i))
+		}
+		if e.Label == _epsilonLabel {
+			c.epsilon++
+		}
+	}
+	c.labelStart = append(c.labelStart, int32(len(c.edges)))
+
+	from := make([]int32, len(c.edges))
+	to := make([]int32, len(c.edges))
+	label := make([]int32, len(c.edges))
+	for l := range c.labels {
+		for i := c.labelStart[l]; i < c.labelStart[l+1]; i++ {
+			from[i], to[i], label[i] = c.ids[c.edges[i].From], c.ids[c.edges[i].To], int32(l)
+		}
+	}
+	c.out = makeCSR(len(c.vertices), from, to, label)
+	c.in = makeCSR(len(c.vertices), to, from, label)
+	return c
+}
+
+// makeCSR groups the edges src[i] -> dst[i] by source with a counting sort.
+// The edges are sorted by label already, so every row is too.
+func makeCSR(n int, src, dst, label []int32) csr {
+	c := csr{
+		offsets: make([]int32, n+1),
+		adj:     make([]int32, len(src)),
+		label:   make([]int32, len(src)),
+	}
+	for _, v := range src {
+		c.offsets[v+1]++
+	}
+	for v := 0; v < n; v++ {
+		c.offsets[v+1] += c.offsets[v]
+	}
+	next := append([]int32{}, c.offsets[:n]...)
+	for i, v := range src {
+		c.adj[next[v]] = dst[i]
+		c.label[next[v]] = label[i]
+		next[v]++
+	}
+	return c
+}
+
+// row returns the neighbours of v with label l
+func (c csr) row(v int32, l int32) []int32 {
+	lo, hi := int(c.offsets[v]), int(c.offsets[v+1])
+	labels := c.label[lo:hi]
+	i := sort.Search(len(labels), func(k int) bool { return labels[k] >= l })
+	j := i
+	for j < len(labels) && labels[j] == l {
+		j++
+	}
+	return c.adj[lo+i : lo+j]
+}
+
+// all returns the neighbours of v
+func (c csr) all(v int32) []int32 {
+	return c.adj[c.offsets[v]:c.offsets[v+1]]
+}
+
+func (c *CSRGraph) NumVertices() int {
+	return len(c.vertices)
+}
+
+// NumEdges returns the number of edges, not counting the epsilon
+// self-loops, as Graph.NumEdges
+func (c *CSRGraph) NumEdges() int {
+	return len(c.edges) - c.epsilon
+}
+
+// Vertices returns the vertices in increasing order, the index of a vertex
+// is its dense id. The slice must not be modified.
+func (c *CSRGraph) Vertices() []Vertex {
+	return c.vertices
+}
+
+// ID returns the dense id of v, false if v is not a vertex.
+func (c *CSRGraph) ID(v Vertex) (int, bool) {
+	id, ok := c.ids[v]
+	return int(id), ok
+}
+
+// GetEdges returns the edges sorted by label. The slice must not be
+// modified.
+func (c *CSRGraph) GetEdges() []Edge {
+	return c.edges
+}
+
+// GetEdgesWithLabel returns the edges with label. The slice must not be
+// modified.
+func (c *CSRGraph) GetEdgesWithLabel(label Label) []Edge {
+	l, ok := c.labelIDs[label]
+	if !ok {
+		return nil
+	}
+	return c.edges[c.labelStart[l]:c.labelStart[l+1]]
+}
+
+// outIDs returns the dense ids of the targets of the edges from v with label
+func (c *CSRGraph) outIDs(v Vertex, label Label) []int32 {
+	id, ok := c.ids[v]
+	l, okLabel := c.labelIDs[label]
+	if !ok || !okLabel {
+		return nil
+	}
+	return c.out.row(id, l)
+}
+
+// inIDs returns the dense ids of the sources of the edges to v with label
+func (c *CSRGraph) inIDs(v Vertex, label Label) []int32 {
+	id, ok := c.ids[v]
+	l, okLabel := c.labelIDs[label]
+	if !ok || !okLabel {
+		return nil
+	}
+	return c.in.row(id, l)
+}
@@ -0,0 +1,146 @@
+package idyck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+// dotText writes g in the dot format, e.g. to read it back with ParseDot
+func dotText(t testing.TB, g *Graph) []byte {
+	var buf bytes.Buffer
+	if err := WriteDot(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sortedEdges(edges []Edge) string {
+	res := append([]Edge{}, edges...)
+	sort.Slice(res, func(i, j int) bool { return fmt.Sprint(res[i]) < fmt.Sprint(res[j]) })
+	return fmt.Sprint(res)
+}
+
+func TestCSRGraphFromEdges(t *testing.T) {
+	loozfon, err := os.ReadFile("../taint/loozfon.dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	random := randomGraph(rng, 30, 120, []Label{"op--0", "cp--0", "ob--0", "cb--0", "normal"})
+	//the duplicate is dropped
+	text := append(dotText(t, random), "3->4[label=\"normal\"]\n3->4[label=\"normal\"]\n"...)
+
+	for name, text := range map[string][]byte{"loozfon": loozfon, "random": text} {
+		t.Run(name, func(t *testing.T) {
+			g, err := ParseDot(bytes.NewReader(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			edges, err := ParseDotEdges(bytes.NewReader(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, got := NewCSRGraph(g), NewCSRGraphFromEdges(edges)
+			if fmt.Sprint(got.Vertices()) != fmt.Sprint(want.Vertices()) || fmt.Sprint(got.labels) != fmt.Sprint(want.labels) {
+				t.Fatalf("vertices or labels differ")
+			}
+			if sortedEdges(got.GetEdges()) != sortedEdges(want.GetEdges()) || got.NumEdges() != want.NumEdges() {
+				t.Fatalf("edges differ")
+			}
+			for _, v := range want.Vertices() {
+				for _, label := range want.labels {
+					if fmt.Sprint(sortedIDs(got.outIDs(v, label))) != fmt.Sprint(sortedIDs(want.outIDs(v, label))) ||
+						fmt.Sprint(sortedIDs(got.inIDs(v, label))) != fmt.Sprint(sortedIDs(want.inIDs(v, label))) {
+						t.Fatalf("adjacency of %d differs", v)
+					}
+				}
+			}
+
+			labelsP, labelsB, _ := ParseDyckComponent(g)
+			m, err := InterleavedDyckGrammar(labelsP, labelsB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			grammar, err := CompileGrammar(&m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantPaths, err := ReachCompiled(context.Background(), g, grammar)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotPaths, err := ReachCSR(context.Background(), got, grammar)
+			if err != nil {
+				t.Fatal(err)
+			}
+			SortPaths(wantPaths)
+			SortPaths(gotPaths)
+			if fmt.Sprint(gotPaths) != fmt.Sprint(wantPaths) {
+				t.Errorf("ReachCSR found %d pairs, ReachCompiled %d", len(gotPaths), len(wantPaths))
+			}
+		})
+	}
+}
+
+func sortedIDs(ids []int32) []int32 {
+	res := append([]int32{}, ids...)
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+// BenchmarkReadGraph compares the memory kept to run the engine on a large
+// graph: the Graph of ParseDot with its CSRGraph, as newReach has them, and
+// the CSRGraph of ParseDotEdges alone. retained-MB is the live heap after
+// reading, bytes/edge the same per edge.
+func BenchmarkReadGraph(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	labels := []Label{"normal"}
+	for i := 0; i < 50; i++ {
+		labels = append(labels, Label(fmt.Sprintf("op--%d", i)), Label(fmt.Sprintf("cp--%d", i)))
+	}
+	const vertices, edges = 50000, 200000
+	text := dotText(b, randomGraph(rng, vertices, edges, labels))
+
+	retained := func(b *testing.B, read func() any) {
+		var kept any
+		var before, after runtime.MemStats
+		for i := 0; i < b.N; i++ {
+			kept = nil
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			kept = read()
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+		}
+		runtime.KeepAlive(kept)
+		bytes := float64(after.HeapAlloc) - float64(before.HeapAlloc)
+		b.ReportMetric(bytes/(1<<20), "retained-MB")
+		b.ReportMetric(bytes/edges, "bytes/edge")
+	}
+	b.Run("Graph+CSR", func(b *testing.B) {
+		b.ReportAllocs()
+		retained(b, func() any {
+			g, err := ParseDot(bytes.NewReader(text))
+			if err != nil {
+				b.Fatal(err)
+			}
+			return []any{g, NewCSRGraph(g)}
+		})
+	})
+	b.Run("CSR", func(b *testing.B) {
+		b.ReportAllocs()
+		retained(b, func() any {
+			edges, err := ParseDotEdges(bytes.NewReader(text))
+			if err != nil {
+				b.Fatal(err)
+			}
+			return NewCSRGraphFromEdges(edges)
+		})
+	})
+}
@@ -0,0 +1,169 @@
+package idyck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotDerived is returned by DerivationDAGOf for a pair that is not
+// derived from the start nonterminal.
+var ErrNotDerived = errors.New("pair not derived from the start nonterminal")
+
+// DerivationNode is a derivation of a DerivationDAG with the rule that
+// derived it first, the derivations of the body of that rule and the edges
+// the rule reads.
+type DerivationNode struct {
+	Nonterminal string
+	Segments    []Path
+	Rule        string
+	Children    []int //indexes in DerivationDAG.Nodes
+	Edges       []Edge
+}
+
+// DerivationDAG shows why S([u v]) was derived. Nodes[0] is S([u v]),
+// derivations used more than once are shared.
+type DerivationDAG struct {
+	Nodes []DerivationNode
+}
+
+// DerivationDAGOf runs the reachability engine on g for m and returns the
+// derivation DAG of S(pair). The grammar is validated first. ErrNotDerived
+// is returned if S(pair) is not derived.
+func DerivationDAGOf(ctx context.Context, g *Graph, m *MCFG, pair Path) (*DerivationDAG, error) {
+	grammar, err := CompileGrammar(m)
+	if err != nil {
+		return nil, err
+	}
+	reachData := newReach(g, grammar, false)
+	reachData.witness = witnesses{}
+	if _, err := reachData.run(ctx); err != nil {
+		return nil, err
+	}
+	root := reachData.segmentsKey(_startID, segments{pair})
+	if _, ok := reachData.witness[root]; !ok {
+		return nil, ErrNotDerived
+	}
+	return reachData.derivationDAG(root), nil
+}
+
+// derivationDAG follows the justifications from root
+func (r *reach) derivationDAG(root derivationKey) *DerivationDAG {
+	keySegments := map[derivationKey]segments{}
+	for _, derivations := range r.derivations {
+		for _, d := range derivations {
+			keySegments[d.key] = d.segments
+		}
+	}
+
+	dag := &DerivationDAG{}
+	index := map[derivationKey]int{}
+	var visit func(key derivationKey) int
+	visit = func(key derivationKey) int {
+		if i, ok := index[key]; ok {
+			return i
+		}
+		i := len(dag.Nodes)
+		index[key] = i
+		j := r.witness[key]
+		node := DerivationNode{
+			Nonterminal: r.grammar.name(key.name),
+			Segments:    append([]Path{}, keySegments[key]...),
+		}
+		if j.kind == basicKind {
+			node.Rule = BasicRule{HeadName: node.Nonterminal, Label: j.edge.Label}.String()
+		} else {
+			node.Rule = j.rule.String()
+		}
+		if j.kind != concatKind {
+			node.Edges = []Edge{j.edge}
+		}
+		dag.Nodes = append(dag.Nodes, node)
+
+		children := []int{}
+		for _, body := range j.bodies {
+			children = append(children, visit(body))
+		}
+		dag.Nodes[i].Children = children
+		return i
+	}
+	visit(root)
+	return dag
+}
+
+func (n DerivationNode) String() string {
+	segs := []string{}
+	for _, p := range n.Segments {
+		segs = append(segs, p.String())
+	}
+	return fmt.Sprintf("%s(%s)", n.Nonterminal, strings.Join(segs, ", "))
+}
+
+// WriteDOT writes the DAG as a DOT digraph, a vertex per derivation
+// labelled with the derivation, the rule and the edges it reads.
+func (d *DerivationDAG) WriteDOT(w io.Writer) error {
+	b := &strings.Builder{}
+	fmt.Fprintln(b, "digraph derivation {")
+	fmt.Fprintln(b, "\tnode [shape=box];")
+	for i, n := range d.Nodes {
+		lines := []string{n.String(), n.Rule}
+		for _, e := range n.Edges {
+			lines = append(lines, fmt.Sprintf("%d->%d %s", e.From, e.To, e.Label))
+		}
+		fmt.Fprintf(b, "\tn%d [label=%q];\n", i, strings.Join(lines, "\n"))
+	}
+	for i, n := range d.Nodes {
+		for _, c := range n.Children {
+			fmt.Fprintf(b, "\tn%d -> n%d;\n", i, c)
+		}
+	}
+	fmt.Fprintln(b, "}")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type dagEdgeJSON struct {
+	From  Vertex `json:"from"`
+	To    Vertex `json:"to"`
+	Label Label  `json:"label"`
+}
+
+type dagNodeJSON struct {
+	ID          int           `json:"id"`
+	Nonterminal string        `json:"nonterminal"`
+	Segments    [][2]Vertex   `json:"segments"`
+	Rule        string        `json:"rule"`
+	Children    []int         `json:"children"`
+	Edges       []dagEdgeJSON `json:"edges"`
+}
+
+// WriteJSON writes the DAG as {"nodes": [...]}, every node with its id,
+// nonterminal, segments as [start, end] pairs, rule, children and edges.
+func (d *DerivationDAG) WriteJSON(w io.Writer) error {
+	nodes := []dagNodeJSON{}
+	for i, n := range d.Nodes {
+		node := dagNodeJSON{
+			ID:          i,
+			Nonterminal: n.Nonterminal,
+			Segments:    [][2]Vertex{},
+			Rule:        n.Rule,
+			Children:    append([]int{}, n.Children...),
+			Edges:       []dagEdgeJSON{},
+		}
+		for _, p := range n.Segments {
+			node.Segments = append(node.Segments, [2]Vertex{p.Start, p.End})
+		}
+		for _, e := range n.Edges {
+			node.Edges = append(node.Edges, dagEdgeJSON{e.From, e.To, e.Label})
+		}
+		nodes = append(nodes, node)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Nodes []dagNodeJSON `json:"nodes"`
+	}{nodes})
+}
@@ -0,0 +1,159 @@
+package idyck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	_dotNode = regexp.MustCompile(`^\tn(\d+) \[label="((?:[^"\\]|\\.)*)"\];$`)
+	_dotEdge = regexp.MustCompile(`^\tn(\d+) -> n(\d+);$`)
+)
+
+// checkDOT checks that text is a digraph with the nodes of dag, the first
+// labelled with root, and an edge per child
+func checkDOT(t *testing.T, text string, dag *DerivationDAG, root string) {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) < 3 || lines[0] != "digraph derivation {" || lines[len(lines)-1] != "}" {
+		t.Fatalf("not a digraph:\n%s", text)
+	}
+	nodes := map[string]bool{}
+	edges := 0
+	for _, line := range lines[2 : len(lines)-1] {
+		if m := _dotNode.FindStringSubmatch(line); m != nil {
+			nodes[m[1]] = true
+			if m[1] == "0" && !strings.HasPrefix(m[2], root+`\n`) {
+				t.Errorf("root labelled %s, want %s", m[2], root)
+			}
+			continue
+		}
+		m := _dotEdge.FindStringSubmatch(line)
+		if m == nil {
+			t.Fatalf("line %q is no node or edge", line)
+		}
+		if !nodes[m[1]] || !nodes[m[2]] {
+			t.Errorf("edge %q between undeclared nodes", line)
+		}
+		edges++
+	}
+	children := 0
+	for _, n := range dag.Nodes {
+		children += len(n.Children)
+	}
+	if len(nodes) != len(dag.Nodes) || edges != children {
+		t.Errorf("%d nodes and %d edges, want %d and %d", len(nodes), edges, len(dag.Nodes), children)
+	}
+}
+
+// checkJSON checks that text decodes to the nodes of dag, rooted at S(pair)
+func checkJSON(t *testing.T, text []byte, dag *DerivationDAG, pair Path) {
+	var decoded struct {
+		Nodes []dagNodeJSON `json:"nodes"`
+	}
+	if err := json.Unmarshal(text, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	nodes := decoded.Nodes
+	if len(nodes) != len(dag.Nodes) {
+		t.Fatalf("%d nodes, want %d", len(nodes), len(dag.Nodes))
+	}
+	root := nodes[0]
+	if root.Nonterminal != "S" || len(root.Segments) != 1 || root.Segments[0] != [2]Vertex{pair.Start, pair.End} {
+		t.Errorf("root %+v, want S(%v)", root, pair)
+	}
+	for i, n := range nodes {
+		if n.ID != i || n.Nonterminal != dag.Nodes[i].Nonterminal || n.Rule != dag.Nodes[i].Rule {
+			t.Errorf("node %d is %+v, want %v", i, n, dag.Nodes[i])
+		}
+		if fmt.Sprint(n.Children) != fmt.Sprint(dag.Nodes[i].Children) || len(n.Edges) != len(dag.Nodes[i].Edges) {
+			t.Errorf("node %d has children %v and edges %v", i, n.Children, n.Edges)
+		}
+	}
+}
+
+// checkDAG checks that every node is reached from the root without a
+// cycle and only reads edges of g
+func checkDAG(t *testing.T, dag *DerivationDAG, g *Graph) {
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make([]int, len(dag.Nodes))
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = active
+		for _, c := range dag.Nodes[i].Children {
+			if c < 0 || c >= len(dag.Nodes) || state[c] == active {
+				t.Fatalf("child %d of node %d is out of range or on a cycle", c, i)
+			}
+			if state[c] == unvisited {
+				visit(c)
+			}
+		}
+		state[i] = done
+	}
+	visit(0)
+	for i, n := range dag.Nodes {
+		if state[i] != done {
+			t.Errorf("node %d %v is not reached from the root", i, n)
+		}
+		for _, e := range n.Edges {
+			if !hasVertex(g.OutEdges(e.From, e.Label), e.To) {
+				t.Errorf("node %d %v reads %v, not an edge of the graph", i, n, e)
+			}
+		}
+	}
+}
+
+func TestDerivationDAG(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	dags := 0
+	for i := 0; i < 10; i++ {
+		grammars, g := dyckGrammars(t, randomGraph(rng, 6, 14, _dyckTestLabels))
+		m := grammars["interleaved"]
+		grammar, err := CompileGrammar(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pairs, err := ReachCompiled(ctx, g, grammar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pair := range pairs {
+			dag, err := DerivationDAGOf(ctx, g, m, pair)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkDAG(t, dag, g)
+
+			var dot, js bytes.Buffer
+			if err := dag.WriteDOT(&dot); err != nil {
+				t.Fatal(err)
+			}
+			checkDOT(t, dot.String(), dag, fmt.Sprintf("S(%v)", pair))
+			if err := dag.WriteJSON(&js); err != nil {
+				t.Fatal(err)
+			}
+			checkJSON(t, js.Bytes(), dag, pair)
+			dags++
+		}
+		for _, v := range g.Vertices() {
+			pair := makePath(v, v+100)
+			if _, err := DerivationDAGOf(ctx, g, m, pair); !errors.Is(err, ErrNotDerived) {
+				t.Errorf("graph %d: DAG of %v: %v", i, pair, err)
+			}
+		}
+	}
+	if dags == 0 {
+		t.Errorf("no pairs derived")
+	}
+}
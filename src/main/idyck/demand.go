@@ -0,0 +1,445 @@
+package idyck
+
+import (
+	"context"
+)
+
+// Goal-directed evaluation derives only what can be part of a derivation of
+// S([u v]) for one pair, in the way of magic sets. Every segment end of
+// every nonterminal is either free or bound. A bound end only takes the
+// values that are demanded for it, and demands flow from the head of a rule
+// to its body: from S([u v]) to the bodies of its rules and, sideways, from
+// the end of a derivation of one body to the start of the next segment of
+// another body in the same concatenation. A derivation whose bound ends are
+// not all demanded is parked until they are.
+
+// goalKind is what the goal of a goal-directed evaluation binds of S([u v])
+type goalKind int
+
+const (
+	pairGoal   goalKind = iota //u and v, see PairReachability
+	sourceGoal                 //u, see SingleSourceReachability
+	sinkGoal                   //v, see SingleSinkReachability
+)
+
+// demandPlan is the goal-directed evaluation of a CompiledGrammar
+type demandPlan struct {
+	bound  [][][2]bool    //nonterminal -> segment -> start, end bound
+	byHead [][]demandRule //the rules with this head
+}
+
+// demandRule is a rule as seen from its head
+type demandRule struct {
+	kind  ruleKind
+	body  int32 //unused for concatenations
+	idx   int   //segment of the prepend, append or insert
+	label Label
+	rule  *concatRule
+}
+
+// sipLink passes the end of segment seg of a derivation of one body of a
+// concatenation as demand for the start of segment bodySeg of body
+type sipLink struct {
+	seg     int
+	body    int
+	bodySeg int
+}
+
+// newDemandPlans returns the plan of every goalKind
+func newDemandPlans(c *CompiledGrammar) [3]*demandPlan {
+	byHead, dims := demandRules(c)
+	plans := [3]*demandPlan{}
+	for goal, ends := range [3][2]bool{pairGoal: {true, true}, sourceGoal: {true, false}, sinkGoal: {false, true}} {
+		plans[goal] = newDemandPlan(byHead, dims, ends)
+	}
+	return plans
+}
+
+// demandRules returns the rules by head and the dimension of every
+// nonterminal
+func demandRules(c *CompiledGrammar) ([][]demandRule, []int) {
+	n := c.NumNonterminals()
+	byHead := make([][]demandRule, n)
+
+	//the dimension of every nonterminal
+	dims := make([]int, n)
+	setDim := func(name int32, dim int) {
+		if dim > dims[name] {
+			dims[name] = dim
+		}
+	}
+	for _, heads := range c.basic {
+		for _, head := range heads {
+			setDim(head, 1)
+		}
+	}
+	concats := map[*concatRule]bool{}
+	for body := range c.names {
+		for _, rule := range c.prepend[body] {
+			setDim(rule.head, rule.Terms)
+			setDim(int32(body), rule.Terms)
+			byHead[rule.head] = append(byHead[rule.head], demandRule{kind: prependKind, body: int32(body), idx: rule.PrependIdx, label: rule.Label})
+		}
+		for _, rule := range c.append[body] {
+			setDim(rule.head, rule.Terms)
+			setDim(int32(body), rule.Terms)
+			byHead[rule.head] = append(byHead[rule.head], demandRule{kind: appendKind, body: int32(body), idx: rule.AppendIdx, label: rule.Label})
+		}
+		for _, rule := range c.insert[body] {
+			setDim(rule.head, rule.OriginalTerms+1)
+			setDim(int32(body), rule.OriginalTerms)
+			byHead[rule.head] = append(byHead[rule.head], demandRule{kind: insertKind, body: int32(body), idx: rule.InsertIdx, label: rule.Label})
+		}
+		for _, use := range c.concat[body] {
+			if concats[use.rule] {
+				continue
+			}
+			concats[use.rule] = true
+			setDim(use.rule.head, len(use.rule.TermConcatenation))
+			for _, term := range use.rule.TermConcatenation {
+				for _, t := range term {
+					setDim(use.rule.bodies[t.FromBodyIdx], t.FromIndexInBody+1)
+				}
+			}
+			use.rule.sip = sipLinks(use.rule)
+			byHead[use.rule.head] = append(byHead[use.rule.head], demandRule{kind: concatKind, rule: use.rule})
+		}
+	}
+	return byHead, dims
+}
+
+// newDemandPlan binds every segment end that is bound in all uses of its
+// nonterminal, the ends of S that are bound by the goal are given
+func newDemandPlan(byHead [][]demandRule, dims []int, goal [2]bool) *demandPlan {
+	p := &demandPlan{
+		bound:  make([][][2]bool, len(dims)),
+		byHead: byHead,
+	}
+	for name := range p.bound {
+		p.bound[name] = make([][2]bool, dims[name])
+		for seg := range p.bound[name] {
+			p.bound[name][seg] = [2]bool{true, true}
+		}
+	}
+	if len(p.bound[_startID]) > 0 {
+		p.bound[_startID][0] = goal
+	}
+
+	//greatest fixpoint: an end stays bound while all its uses bind it
+	changed := true
+	restrict := func(name int32, seg int, side int, bound bool) {
+		if !bound && p.bound[name][seg][side] {
+			p.bound[name][seg][side] = false
+			changed = true
+		}
+	}
+	for changed {
+		changed = false
+		for head, rules := range p.byHead {
+			headBound := p.bound[head]
+			for _, rule := range rules {
+				switch rule.kind {
+				case prependKind, appendKind:
+					for seg := range p.bound[rule.body] {
+						restrict(rule.body, seg, 0, headBound[seg][0])
+						restrict(rule.body, seg, 1, headBound[seg][1])
+					}
+				case insertKind:
+					for seg := range p.bound[rule.body] {
+						headSeg := seg
+						if seg >= rule.idx {
+							headSeg++
+						}
+						restrict(rule.body, seg, 0, headBound[headSeg][0])
+						restrict(rule.body, seg, 1, headBound[headSeg][1])
+					}
+				case concatKind:
+					used := make([][][2]bool, len(rule.rule.bodies))
+					for i, body := range rule.rule.bodies {
+						used[i] = make([][2]bool, len(p.bound[body]))
+					}
+					for i, term := range rule.rule.TermConcatenation {
+						for k, t := range term {
+							body := rule.rule.bodies[t.FromBodyIdx]
+							used[t.FromBodyIdx][t.FromIndexInBody] = [2]bool{true, true}
+							if k == 0 {
+								restrict(body, t.FromIndexInBody, 0, headBound[i][0])
+							} else if !rule.rule.sipInto(t.FromBodyIdx, t.FromIndexInBody) {
+								restrict(body, t.FromIndexInBody, 0, false)
+							}
+							if k == len(term)-1 {
+								restrict(body, t.FromIndexInBody, 1, headBound[i][1])
+							} else {
+								restrict(body, t.FromIndexInBody, 1, false)
+							}
+						}
+					}
+					//segments that are not used are never demanded
+					for i, segs := range used {
+						for seg, u := range segs {
+							if !u[0] {
+								restrict(rule.rule.bodies[i], seg, 0, false)
+								restrict(rule.rule.bodies[i], seg, 1, false)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return p
+}
+
+// sipLinks chooses for every segment of a concatenation that follows a
+// segment of another body whether its start is demanded by that body. The
+// links between bodies are kept acyclic, else bodies could wait for each
+// other forever.
+func sipLinks(rule *concatRule) [][]sipLink {
+	links := make([][]sipLink, len(rule.bodies))
+	reaches := func(from int, to int) bool {
+		seen := map[int]bool{from: true}
+		stack := []int{from}
+		for len(stack) > 0 {
+			b := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if b == to {
+				return true
+			}
+			for _, l := range links[b] {
+				if !seen[l.body] {
+					seen[l.body] = true
+					stack = append(stack, l.body)
+				}
+			}
+		}
+		return false
+	}
+	for _, term := range rule.TermConcatenation {
+		for k := 1; k < len(term); k++ {
+			from, to := term[k-1], term[k]
+			if from.FromBodyIdx == to.FromBodyIdx || reaches(to.FromBodyIdx, from.FromBodyIdx) {
+				continue
+			}
+			links[from.FromBodyIdx] = append(links[from.FromBodyIdx], sipLink{
+				seg:     from.FromIndexInBody,
+				body:    to.FromBodyIdx,
+				bodySeg: to.FromIndexInBody,
+			})
+		}
+	}
+	return links
+}
+
+// sipInto reports whether the start of segment seg of body is demanded by
+// another body
+func (rule *concatRule) sipInto(body int, seg int) bool {
+	for _, links := range rule.sip {
+		for _, l := range links {
+			if l.body == body && l.bodySeg == seg {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// demandKey is a value demanded for the start or the end of a segment of
+// a nonterminal
+type demandKey struct {
+	name   int32
+	seg    int32
+	end    bool
+	vertex Vertex
+}
+
+// demand is the state of a goal-directed evaluation
+type demand struct {
+	plan     *demandPlan
+	demanded map[demandKey]bool
+	queue    []demandKey //demanded, not propagated yet
+	pending  map[demandKey][]derivation
+	parked   map[derivationKey]bool
+	why      map[derivationKey]justification //of the parked derivations, if witnesses are wanted
+}
+
+func newDemand(plan *demandPlan) *demand {
+	return &demand{
+		plan:     plan,
+		demanded: map[demandKey]bool{},
+		pending:  map[demandKey][]derivation{},
+		parked:   map[derivationKey]bool{},
+		why:      map[derivationKey]justification{},
+	}
+}
+
+// newGoalReach returns a reach that derives only what is needed for
+// S([goal.Start goal.End])
+func newGoalReach(g *Graph, grammar *CompiledGrammar, recordEdge bool, goal Path) *reach {
+	r := newReach(g, grammar, recordEdge)
+	r.demand = newDemand(grammar.demand[pairGoal])
+	r.demand.add(demandKey{name: _startID, seg: 0, end: false, vertex: goal.Start})
+	r.demand.add(demandKey{name: _startID, seg: 0, end: true, vertex: goal.End})
+	return r
+}
+
+// newAnchoredReach returns a reach that derives only what is needed for
+// S([v u]), any u, for the vertices v of an; or S([u v]) if an.sink. It
+// can derive S for other pairs too, which the callers drop.
+func newAnchoredReach(g *Graph, grammar *CompiledGrammar, recordEdge bool, an *anchor) *reach {
+	r := newReach(g, grammar, recordEdge)
+	goal := sourceGoal
+	if an.sink {
+		goal = sinkGoal
+	}
+	r.demand = newDemand(grammar.demand[goal])
+	for _, v := range an.vertices {
+		r.demand.add(demandKey{name: _startID, seg: 0, end: an.sink, vertex: v})
+	}
+	return r
+}
+
+// add demands key if its segment end is bound
+func (d *demand) add(key demandKey) {
+	bound := d.plan.bound[key.name]
+	if int(key.seg) >= len(bound) || d.demanded[key] {
+		return
+	}
+	side := 0
+	if key.end {
+		side = 1
+	}
+	if !bound[key.seg][side] {
+		return
+	}
+	d.demanded[key] = true
+	d.queue = append(d.queue, key)
+}
+
+// admits reports whether the bound ends of toAdd are demanded, else it is
+// parked under the first end that is not
+func (d *demand) admits(toAdd *derivation) bool {
+	bound := d.plan.bound[toAdd.key.name]
+	for i, segment := range toAdd.segments {
+		for side, vertex := range [2]Vertex{segment.Start, segment.End} {
+			if !bound[i][side] {
+				continue
+			}
+			key := demandKey{name: toAdd.key.name, seg: int32(i), end: side == 1, vertex: vertex}
+			if d.demanded[key] {
+				continue
+			}
+			if !d.parked[toAdd.key] {
+				d.parked[toAdd.key] = true
+				d.pending[key] = append(d.pending[key], *toAdd)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// justify keeps j for the derivation key while it is parked, unless it has
+// a justification already
+func (d *demand) justify(key derivationKey, j justification) {
+	if !d.parked[key] {
+		return
+	}
+	if _, ok := d.why[key]; !ok {
+		d.why[key] = j
+	}
+}
+
+// supply passes the ends of a new derivation sideways, see sipLink
+func (d *demand) supply(r *reach, added *derivation) {
+	for _, use := range r.grammar.concat[added.key.name] {
+		for _, l := range use.rule.sip[use.idx] {
+			d.add(demandKey{
+				name:   use.rule.bodies[l.body],
+				seg:    int32(l.bodySeg),
+				end:    false,
+				vertex: added.segments[l.seg].End,
+			})
+		}
+	}
+}
+
+// drain propagates the new demands to the bodies of the rules and adds the
+// derivations parked on them, with the justification they were parked with
+func (d *demand) drain(r *reach) {
+	for len(d.queue) > 0 {
+		key := d.queue[len(d.queue)-1]
+		d.queue = d.queue[:len(d.queue)-1]
+		d.propagate(r, key)
+
+		parked := d.pending[key]
+		delete(d.pending, key)
+		for i := range parked {
+			key := parked[i].key
+			delete(d.parked, key)
+			if r.addDerivation(&parked[i]) && r.witness != nil {
+				r.witness[key] = d.why[key]
+			}
+			//kept while it is parked again on another end
+			if !d.parked[key] {
+				delete(d.why, key)
+			}
+		}
+	}
+}
+
+// propagate demands what the rules with head key.name need from their
+// bodies for key
+func (d *demand) propagate(r *reach, key demandKey) {
+	seg := int(key.seg)
+	for _, rule := range d.plan.byHead[key.name] {
+		switch rule.kind {
+		case prependKind:
+			if seg == rule.idx && !key.end {
+				for _, id := range r.graph.outIDs(key.vertex, rule.label) {
+					d.add(demandKey{rule.body, key.seg, false, r.graph.vertices[id]})
+				}
+				continue
+			}
+			d.add(demandKey{rule.body, key.seg, key.end, key.vertex})
+		case appendKind:
+			if seg == rule.idx && key.end {
+				for _, id := range r.graph.inIDs(key.vertex, rule.label) {
+					d.add(demandKey{rule.body, key.seg, true, r.graph.vertices[id]})
+				}
+				continue
+			}
+			d.add(demandKey{rule.body, key.seg, key.end, key.vertex})
+		case insertKind:
+			if seg == rule.idx {
+				continue
+			}
+			bodySeg := key.seg
+			if seg > rule.idx {
+				bodySeg--
+			}
+			d.add(demandKey{rule.body, bodySeg, key.end, key.vertex})
+		case concatKind:
+			term := rule.rule.TermConcatenation[seg]
+			t := term[0]
+			if key.end {
+				t = term[len(term)-1]
+			}
+			d.add(demandKey{rule.rule.bodies[t.FromBodyIdx], int32(t.FromIndexInBody), key.end, key.vertex})
+		}
+	}
+}
+
+// PairReachability reports whether S([pair.Start pair.End]) is derivable
+// for m on g. Unlike AllPairsReachability it derives only what a
+// derivation of this pair can use.
+func PairReachability(ctx context.Context, g *Graph, m *MCFG, pair Path) (bool, error) {
+	grammar, err := CompileGrammar(m)
+	if err != nil {
+		return false, err
+	}
+	paths, err := newGoalReach(g, grammar, false, pair).run(ctx)
+	if err != nil {
+		return false, err
+	}
+	return containsPath(paths, pair), nil
+}
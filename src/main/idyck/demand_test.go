@@ -0,0 +1,111 @@
+package idyck
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestPairReachability(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		grammars, g := dyckGrammars(t, randomGraph(rng, 5, 14, _dyckTestLabels))
+		for name, m := range grammars {
+			all, _, err := AllPairsReachability(ctx, g, m, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, u := range g.Vertices() {
+				for _, v := range g.Vertices() {
+					pair := makePath(u, v)
+					got, err := PairReachability(ctx, g, m, pair)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if want := containsPath(all, pair); got != want {
+						t.Errorf("graph %d, %s, pair %v: got %v, want %v", i, name, pair, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+// balanced reports whether the parentheses and the brackets of the labels
+// of edges are balanced each, ignoring the other labels
+func balanced(edges []Edge) bool {
+	stacks := map[string][]string{}
+	for _, e := range edges {
+		label := string(e.Label)
+		kind, idx, ok := strings.Cut(label, "--")
+		if !ok {
+			continue
+		}
+		family, open := kind[1:], kind[0] == 'o'
+		if open {
+			stacks[family] = append(stacks[family], idx)
+			continue
+		}
+		stack := stacks[family]
+		if len(stack) == 0 || stack[len(stack)-1] != idx {
+			return false
+		}
+		stacks[family] = stack[:len(stack)-1]
+	}
+	for _, stack := range stacks {
+		if len(stack) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func hasVertex(vs VertexList, v Vertex) bool {
+	for _, u := range vs {
+		if u == v {
+			return true
+		}
+	}
+	return false
+}
+
+// TestGoalReachWitness checks that a goal-directed run justifies the pair,
+// also through the derivations it parked
+func TestGoalReachWitness(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 20; i++ {
+		grammars, g := dyckGrammars(t, randomGraph(rng, 6, 18, _dyckTestLabels))
+		grammar, err := CompileGrammar(grammars["interleaved"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		all, err := ReachCompiled(ctx, g, grammar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pair := range all {
+			r := newGoalReach(g, grammar, false, pair)
+			r.witness = witnesses{}
+			if _, err := r.run(ctx); err != nil {
+				t.Fatal(err)
+			}
+			edges, ok := r.witnessPath(pair)
+			if !ok {
+				t.Fatalf("graph %d: no witness for %v", i, pair)
+			}
+			at := pair.Start
+			for _, e := range edges {
+				if e.From != at || !hasVertex(g.OutEdges(e.From, e.Label), e.To) {
+					t.Fatalf("graph %d: witness %v of %v is not a path of the graph", i, edges, pair)
+				}
+				at = e.To
+			}
+			if at != pair.End || !balanced(edges) {
+				t.Fatalf("graph %d: witness %v of %v is not a balanced path", i, edges, pair)
+			}
+		}
+	}
+}
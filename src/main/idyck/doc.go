@@ -0,0 +1,33 @@
+// Package idyck implements the approximations for interleaved Dyck
+// reachability described in "A Better Approximation for Interleaved Dyck
+// Reachability".
+//
+// Graphs are read with ParseDotFile or built with MakeGraph and AddEdge.
+// The reachability engine works on a CSRGraph, a compact immutable copy
+// with dense vertex and label ids made by NewCSRGraph. For large graphs
+// ReadDotFileEdges and NewCSRGraphFromEdges build it without the Graph,
+// which takes several times its memory, and ReachCSR runs on it. Grammars
+// are MCFGs in normal form, built by ParseNormalForm or by the Dyck grammar
+// constructors. ParseMCFG gives the syntax tree of a grammar in the .mcfg
+// surface syntax, with positions for error reporting. Both the syntax tree
+// and MCFG have Validate. CompileGrammar numbers the nonterminals and
+// indexes the rules of an MCFG once, for ReachCompiled to use on many
+// graphs. AllPairsReachability computes the pairs derivable from the start
+// nonterminal S, and Run executes the whole approximation pipeline. Their
+// single-source and single-sink variants (SingleSourceReachability,
+// SingleSinkReachability, RunFromSource and RunToSink) work on the slice of
+// the graph around one vertex and derive S only from, or to, that vertex,
+// in the goal-directed way of PairReachability. PairReachability decides a
+// single pair goal-directed, making only the derivations that a derivation
+// of the pair can use; Query and the on-demand stage refine their pairs
+// this way unless Config.BottomUp is set. Query decides a single pair.
+// Witness gives the edges of a balanced path for a pair of the
+// under-approximation, and DerivationDAGOf the derivations that derive a
+// pair from S under any grammar. Update reanalyzes a graph changed by a
+// Delta from the result of an earlier run, only on the components that
+// changed. A LabelSchema translates the labels of graphs from other
+// frontends to the op--N, cp--N, ob--N, cb--N and normal labels the
+// analyses read. AllPairsReachability, RunContext and Query stop when their
+// context is cancelled and return what they found so far together with the
+// context's error.
+package idyck
@@ -1,4 +1,4 @@
-package main
+package idyck
 
 import (
 	"fmt"
@@ -74,7 +74,7 @@ func (m MCFG) String() string {
 		ruleStrings = append(ruleStrings, rule.String())
 	}
 	for _, rule := range m.ConcatenateRules {
-		ruleStrings = append(ruleStrings, rule.String())
+		ruleStrings = append(ruleStrings, concatenateRuleContext{rule}.String())
 	}
 	return strings.Join(ruleStrings, "\n")
 }
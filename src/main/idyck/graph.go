@@ -1,13 +1,14 @@
-package main
+package idyck
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
 const _epsilonLabel = "" //empty string
 
-type graph struct {
+type Graph struct {
 	outEdges     VertexMap
 	inEdges      VertexMap
 	edgeList     []Edge
@@ -33,8 +34,8 @@ type LabelToVertexList map[Label]VertexList
 
 type VertexMap map[Vertex]LabelToVertexList
 
-func MakeGraph() *graph {
-	return &graph{
+func MakeGraph() *Graph {
+	return &Graph{
 		outEdges:     VertexMap{},
 		inEdges:      VertexMap{},
 		edgeList:     []Edge{},
@@ -43,7 +44,7 @@ func MakeGraph() *graph {
 	}
 }
 
-func (g *graph) AddEdge(from Vertex, to Vertex, label Label) {
+func (g *Graph) AddEdge(from Vertex, to Vertex, label Label) {
 	g.outEdges.addEdge(from, to, label)
 	g.inEdges.addEdge(to, from, label)
 
@@ -70,6 +71,14 @@ func (g *graph) AddEdge(from Vertex, to Vertex, label Label) {
 	}
 }
 
+// addVertex adds v with its epsilon self-loop, like AddEdge
+func (g *Graph) addVertex(v Vertex) {
+	if !g.vertices[v] {
+		g.vertices[v] = true
+		g.AddEdge(v, v, _epsilonLabel)
+	}
+}
+
 func (v VertexMap) addEdge(from Vertex, to Vertex, label Label) {
 	if _, ok := v[from]; !ok {
 		v[from] = LabelToVertexList{}
@@ -85,7 +94,7 @@ func (l LabelToVertexList) addEdge(to Vertex, label Label) {
 }
 
 // InEdges returns the incoming edges to 'to' with a given label
-func (g *graph) InEdges(to Vertex, label Label) VertexList {
+func (g *Graph) InEdges(to Vertex, label Label) VertexList {
 	if to == ANY_VERTEX {
 		res := []Vertex{}
 		for _, el := range g.GetEdgesWithLabel(label) {
@@ -97,7 +106,7 @@ func (g *graph) InEdges(to Vertex, label Label) VertexList {
 }
 
 // InEdgesUnlabeled returns the incoming edges to 'to'
-func (g *graph) InEdgesUnlabeled(to Vertex) VertexList {
+func (g *Graph) InEdgesUnlabeled(to Vertex) VertexList {
     if to == ANY_VERTEX {
         res := []Vertex{}
         for _, el := range g.GetEdges() {
@@ -109,7 +118,7 @@ func (g *graph) InEdgesUnlabeled(to Vertex) VertexList {
 }
 
 // OutEdges returns the outgoing edges from 'from' with a given label
-func (g *graph) OutEdges(from Vertex, label Label) VertexList {
+func (g *Graph) OutEdges(from Vertex, label Label) VertexList {
 	if from == ANY_VERTEX {
 		res := []Vertex{}
 		for _, el := range g.GetEdgesWithLabel(label) {
@@ -121,7 +130,7 @@ func (g *graph) OutEdges(from Vertex, label Label) VertexList {
 }
 
 // OutEdges returns the outgoing edges from 'from'
-func (g *graph) OutEdgesUnlabeled(from Vertex) VertexList {
+func (g *Graph) OutEdgesUnlabeled(from Vertex) VertexList {
 	if from == ANY_VERTEX {
 		res := []Vertex{}
 		for _, el := range g.GetEdges() {
@@ -157,23 +166,23 @@ func (v VertexMap) getEdgesUnlabeled(a Vertex) VertexList {
 	return vtxList
 }
 
-func (g *graph) GetEdges() []Edge {
+func (g *Graph) GetEdges() []Edge {
 	return g.edgeList
 }
 
-func (g *graph) GetEdgesWithLabel(label Label) []Edge {
+func (g *Graph) GetEdgesWithLabel(label Label) []Edge {
 	return g.labelToEdges[label]
 }
 
 // Takes input in two modes:
-func MakeLinearGraph(path string) *graph {
+func MakeLinearGraph(path string) *Graph {
 	if strings.Contains(path, " ") {
 		return makeLinearGraphMultiCharAlphabet(path)
 	}
 	return makeLinearGraphSingleCharAlphabet(path)
 }
 
-func makeLinearGraphSingleCharAlphabet(path string) *graph {
+func makeLinearGraphSingleCharAlphabet(path string) *Graph {
 	g := MakeGraph()
 	for pos, char := range path {
 		g.AddEdge(Vertex(pos), Vertex(pos+1), Label(char))
@@ -181,7 +190,7 @@ func makeLinearGraphSingleCharAlphabet(path string) *graph {
 	return g
 }
 
-func makeLinearGraphMultiCharAlphabet(path string) *graph {
+func makeLinearGraphMultiCharAlphabet(path string) *Graph {
 	g := MakeGraph()
 	for pos, char := range strings.Split(path, " ") {
 		g.AddEdge(Vertex(pos), Vertex(pos+1), Label(char))
@@ -189,137 +198,85 @@ func makeLinearGraphMultiCharAlphabet(path string) *graph {
 	return g
 }
 
-func (g *graph) NumVertices() int {
+// Vertices returns the vertices of g in no particular order
+func (g *Graph) Vertices() []Vertex {
+	res := []Vertex{}
+	for v := range g.vertices {
+		res = append(res, v)
+	}
+	return res
+}
+
+func (g *Graph) NumVertices() int {
 	return len(g.vertices)
 }
 
-func (g *graph) ShortDescription() string {
+// NumEdges returns the number of edges of g, not counting the epsilon
+// self-loops added for every vertex
+func (g *Graph) NumEdges() int {
+	return len(g.edgeList) - len(g.labelToEdges[_epsilonLabel])
+}
+
+func (g *Graph) ShortDescription() string {
 	return fmt.Sprintf("%d vertices, %d edges", g.NumVertices(), len(g.edgeList))
 }
 
-func (g *graph) splitComponents() []*graph {
+func (g *Graph) splitComponents() []*Graph {
 
 	currentComponent := 0
 	vertexComponent := map[Vertex]int{}
 	seen := map[Vertex]bool{}
 
-	var dfs func(v Vertex)
-	dfs = func(v Vertex) {
-		if seen[v] {
-			return
+	//depth-first search with an explicit stack, value-flow graphs have
+	//chains too long for recursion
+	stack := []Vertex{}
+	dfs := func(root Vertex) {
+		seen[root] = true
+		stack = append(stack, root)
+		for len(stack) > 0 {
+			v := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			vertexComponent[v] = currentComponent
+			for _, w := range g.OutEdgesUnlabeled(v) {
+				if !seen[w] {
+					seen[w] = true
+					stack = append(stack, w)
+				}
+			}
+			for _, w := range g.InEdgesUnlabeled(v) {
+				if !seen[w] {
+					seen[w] = true
+					stack = append(stack, w)
+				}
+			}
 		}
-		seen[v] = true
-		vertexComponent[v] = currentComponent
-		for _, w := range g.OutEdgesUnlabeled(v) { dfs(w) }
-		for _, w := range g.InEdgesUnlabeled(v) { dfs(w) }
 	}
 
-	for v, _ := range g.vertices {
-		if _, ok := seen[v]; !ok { 
+	//components are numbered by their smallest vertex so that results
+	//merged in component order do not depend on map iteration
+	vertices := g.Vertices()
+	sort.Slice(vertices, func(i, j int) bool { return vertices[i] < vertices[j] })
+	for _, v := range vertices {
+		if _, ok := seen[v]; !ok {
 			dfs(v)
-			currentComponent++;
+			currentComponent++
 		}
 	}
 
-	components := make([]*graph, currentComponent)
+	components := make([]*Graph, currentComponent)
 	for i, _ := range components {
-		components[i] = MakeGraph() 
+		components[i] = MakeGraph()
 	}
 
 	for _, e := range g.edgeList {
 		if len(e.Label) == 0 && e.From == e.To {
 			continue
 		}
-		components[vertexComponent[e.From]].AddEdge(e.From,e.To,e.Label)
+		components[vertexComponent[e.From]].AddEdge(e.From, e.To, e.Label)
 	}
 
 	return components
 
-} 
-
-func (g *graph) findSccs() (map[Vertex]int, map[[2]int]bool) {
-	index := 0
-	vertexIndex := map[Vertex]int{}
-	vertexLowlink := map[Vertex]int{}
-	vertexOnStack := map[Vertex]bool{}
-
-	sccIndex := 0
-	vertexToSCC := map[Vertex]int{}
-
-	S := []Vertex{}
-
-	var connected func(v Vertex)
-	connected = func(v Vertex) {
-		vertexIndex[v] = index
-		vertexLowlink[v] = index
-		index++
-		S = append(S,v)
-		vertexOnStack[v] = true
-		//fmt.Println("edges", int(v), len(g.OutEdgesUnlabeled(v)))
-		//check outedgesunlabeled
-		for _, w := range g.OutEdgesUnlabeled(v) {
-			//fmt.Println("--",int(v),int(w))
-			if _, ok := vertexIndex[w]; !ok {
-				connected(w)
-				if vertexLowlink[w] < vertexLowlink[v] {
-					vertexLowlink[v] = vertexLowlink[w]
-				}
-			} else if vertexOnStack[w] {
-				if vertexIndex[w]< vertexLowlink[v] {
-					vertexLowlink[v] = vertexIndex[w]
-				}
-			}
-		}
-		if vertexLowlink[v] == vertexIndex[v] {
-			//oldlen := len(S)
-			for {
-				w := S[len(S)-1]
-				S = S[:len(S)-1]
-				vertexOnStack[w] = false
-				vertexToSCC[w] = sccIndex
-				if w == v {
-					break
-				}
-			}
-			sccIndex++
-		}
-	}
-
-	for v, _ := range g.vertices {
-		if _, ok := vertexIndex[v]; !ok {
-			connected(v)
-		}
-	} 
-
-	reach := map[[2]int]bool{}
-	outEdges := map[int][]int{}
-
-	for i := 0; i < sccIndex; i++ {
-		outEdges[i] = []int{}
-	}
-
-	for v, _ := range g.vertices {
-		vcc := vertexToSCC[v]
-		reach[[2]int{vcc,vcc}] = true
-		for _, w := range g.OutEdgesUnlabeled(v) {
-			wcc := vertexToSCC[w]
-			if !reach[[2]int{vcc,wcc}] {
-				reach[[2]int{vcc,wcc}] = true
-				outEdges[vcc] = append(outEdges[vcc],wcc)
-			}
-		}
-	}
-
-	for i := 0; i < sccIndex; i++ {
-		for _, j := range outEdges[i] {
-			for k := 0; k < j; k++ {
-				if reach[[2]int{i,j}] && reach[[2]int{j,k}] {
-					reach[[2]int{i,k}] = true
-				}
-			}
-		}
-	}
-
-	return vertexToSCC, reach
 }
 
+
@@ -0,0 +1,224 @@
+package idyck
+
+import (
+	"context"
+	"sort"
+	"fmt"
+	"encoding/binary"
+)
+
+//the compiled grammars are kept, mutual refinement asks for the same
+//labels many times
+func (a *Analyzer) getAlphaGrammar(labelsP []int, labelsB []int) *CompiledGrammar {
+	key := fmt.Sprint("alpha", a.grammar, a.parityK, labelsP, labelsB)
+	if c, ok := a.grammars[key]; ok {
+		return c
+	}
+	var alphaGrammar MCFG
+	if a.grammar == Augmented {
+		alphaGrammar, _ = DyckAlphaGrammarKParity(labelsP, labelsB, a.parityK)
+	} else {
+		alphaGrammar, _ = DyckAlphaGrammar(labelsP, labelsB)
+	}
+	a.grammars[key] = compileGrammar(&alphaGrammar)
+	return a.grammars[key]
+}
+
+func (a *Analyzer) getBetaGrammar(labelsP []int, labelsB []int) *CompiledGrammar {
+	key := fmt.Sprint("beta", a.grammar, a.parityK, labelsP, labelsB)
+	if c, ok := a.grammars[key]; ok {
+		return c
+	}
+	var betaGrammar MCFG
+	if a.grammar == Augmented {
+		betaGrammar, _ = DyckBetaGrammarKParity(labelsP, labelsB, a.parityK)
+	} else {
+		betaGrammar, _ = DyckBetaGrammar(labelsP, labelsB)
+	}
+	a.grammars[key] = compileGrammar(&betaGrammar)
+	return a.grammars[key]
+}
+
+// SortPaths sorts paths by start and then by end vertex.
+func SortPaths(paths []Path) {
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].Start != paths[j].Start {
+			return paths[i].Start < paths[j].Start
+		}
+		return paths[i].End < paths[j].End
+	})
+}
+
+func findPMR(v Vertex, parent *map[Vertex]Vertex) Vertex {
+	if v != (*parent)[v] {
+		(*parent)[v] = findPMR((*parent)[v], parent)
+	} 
+	return (*parent)[v]
+}
+
+func joinPMR(v Vertex, u Vertex, parent *map[Vertex]Vertex, weight *map[Vertex]int) {
+	v = findPMR(v, parent)
+	u = findPMR(u, parent)
+	if v == u {
+		return
+	}
+	if (*weight)[v] < (*weight)[u] {
+		v, u = u, v
+	}
+	(*weight)[v] += (*weight)[u]
+	(*parent)[u] = v
+}
+
+func condensateFromUnderApprox(g *Graph, underApprox []Path) (*Graph, map[Vertex]Vertex){
+	parent := make(map[Vertex]Vertex)
+	weight := make(map[Vertex]int)
+
+	for v, _ := range g.vertices {
+		parent[v] = v
+		weight[v] = 1
+	}
+
+	underMap := make(map[Vertex]map[Vertex]bool)
+	for _, pair := range underApprox {
+		if _, ok := parent[pair.Start]; !ok { continue }
+		if _, ok := parent[pair.End]; !ok { continue }
+		fv := findPMR(pair.Start, &parent)
+		lv := findPMR(pair.End, &parent)
+		if fv == lv {
+			continue
+		}
+		if _, ok := underMap[fv]; !ok {
+			underMap[fv] = make(map[Vertex]bool)
+		}
+		if _, ok := underMap[lv]; !ok {
+			underMap[lv] = make(map[Vertex]bool)
+		}
+		underMap[fv][lv] = true
+		if underMap[fv][lv] && underMap[lv][fv] {
+			joinPMR(fv, lv, &parent, &weight)
+		}
+	}
+
+	condensedGraph := MakeGraph()
+	exists := make(map[Edge]bool)
+
+	for _, e := range g.GetEdges() {
+		fV := findPMR(e.From, &parent)
+		tV := findPMR(e.To, &parent)
+		newEdge := Edge{
+			From:  fV,
+			To:    tV,
+			Label: e.Label,
+		}
+		if len(e.Label)==0 || exists[newEdge] {
+			continue
+		}
+		exists[newEdge] = true
+		condensedGraph.AddEdge(newEdge.From, newEdge.To, newEdge.Label)
+	}
+
+	return condensedGraph, parent
+}
+
+func getGraphFromEdgeMap(edgeMap map[Edge]bool) (*Graph) {
+	newGraph := MakeGraph()
+	for edge, _ := range edgeMap {
+		newGraph.AddEdge(edge.From, edge.To, edge.Label)
+	}
+	return newGraph
+}
+
+// edgeKey writes the labelled edges of g, sorted and without duplicates,
+// into a string that identifies the edge set exactly: the label lengths are
+// part of the key
+func (g *Graph) edgeKey() string {
+	edges := []Edge{}
+	for _, edge := range g.edgeList {
+		if len(edge.Label) > 0 {
+			edges = append(edges, edge)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		if edges[i].To != edges[j].To {
+			return edges[i].To < edges[j].To
+		}
+		return edges[i].Label < edges[j].Label
+	})
+	key := []byte{}
+	for i, edge := range edges {
+		if i > 0 && edge == edges[i-1] {
+			continue
+		}
+		key = binary.AppendVarint(key, int64(edge.From))
+		key = binary.AppendVarint(key, int64(edge.To))
+		key = binary.AppendUvarint(key, uint64(len(edge.Label)))
+		key = append(key, edge.Label...)
+	}
+	return string(key)
+}
+
+
+//remember to always update deritoEdge and deritoDeri
+func (a *Analyzer) getAlphaPaths(ctx context.Context, g *Graph, labelsP []int, labelsB []int, goal *Path) ([]Path, error) {
+	key := a.newMemoKey(g, goal)
+	if !a.alphaSeenMap[key] {
+		//fmt.Println("running alpha", labelsP, labelsB)
+		alphaGrammar := a.getAlphaGrammar(labelsP, labelsB)
+		alphaPaths, err := a.allPairsReachability(ctx, g, alphaGrammar, goal)
+		if err != nil {
+			return nil, err
+		}
+		a.alphaSeenMap[key] = true
+		a.filterUsedEdges(&alphaPaths)
+		a.alphaProvMap[key] = a.prov
+		a.alphaPathsMap[key] = alphaPaths
+	} else {
+		a.prov = a.alphaProvMap[key]
+	}
+	return a.alphaPathsMap[key], nil
+}
+
+func (a *Analyzer) getBetaPaths(ctx context.Context, g *Graph, labelsP []int, labelsB []int, goal *Path) ([]Path, error) {
+	key := a.newMemoKey(g, goal)
+	if !a.betaSeenMap[key] {
+		betaGrammar := a.getBetaGrammar(labelsP,labelsB)
+		betaPaths, err := a.allPairsReachability(ctx, g, betaGrammar, goal)
+		if err != nil {
+			return nil, err
+		}
+		a.betaSeenMap[key] = true
+		a.filterUsedEdges(&betaPaths)
+		a.betaProvMap[key] = a.prov
+		a.betaPathsMap[key] = betaPaths
+	} else {
+		a.prov = a.betaProvMap[key]
+	}
+	return a.betaPathsMap[key], nil
+}
+
+func (a *Analyzer) newMemoKey(g *Graph, goal *Path) memoKey {
+	edges := g.edgeKey()
+	graph, ok := a.graphIDs[edges]
+	if !ok {
+		graph = int32(len(a.graphIDs))
+		a.graphIDs[edges] = graph
+	}
+	if goal == nil {
+		return memoKey{graph: graph}
+	}
+	return memoKey{graph: graph, goal: *goal, directed: true}
+}
+
+func (a *Analyzer) clearMaps() {
+	a.graphIDs = map[string]int32{}
+	a.alphaSeenMap = map[memoKey]bool{}
+	a.alphaProvMap = map[memoKey]*provenance{}
+	a.alphaPathsMap = map[memoKey][]Path{}
+	a.betaSeenMap = map[memoKey]bool{}
+	a.betaProvMap = map[memoKey]*provenance{}
+	a.betaPathsMap = map[memoKey][]Path{}
+	a.prov = newProvenance()
+}
@@ -1,4 +1,4 @@
-package main
+package idyck
 
 import (
 	"strconv"
@@ -89,7 +89,7 @@ func mk_dummy_brackets(name string, labelsB []int) string {
     return d
 }
 
-func dyck_alpha_grammar(labelsP []int, labelsB []int) (MCFG, error) {
+func DyckAlphaGrammar(labelsP []int, labelsB []int) (MCFG, error) {
 	var d = ""
 	var res = &d
 	writeLine(mk_parenthesis(labelsP),res)
@@ -116,7 +116,7 @@ func int_to_par(bitset int, k int) string {
 	return s
 }
 
-func dyck_alpha_grammar_k_parity_se(labelsP []int, labelsB []int, k int) (MCFG, error) {
+func DyckAlphaGrammarKParity(labelsP []int, labelsB []int, k int) (MCFG, error) {
 
 	var d = ""
 	var res = &d
@@ -132,7 +132,6 @@ func dyck_alpha_grammar_k_parity_se(labelsP []int, labelsB []int, k int) (MCFG,
 	writeLine(mk_parenthesis(labelsP),res)
 	writeLine("Se(eps).",res)
 	writeLine("Se(normal).",res)
-	writeLine("Eps(eps).",res)
 	//writeLine(mk_dummy_brackets("Si",labelsB),res)
 	emptyNum := 0
 	for _, val := range labelsB {
@@ -179,15 +178,15 @@ func dyck_alpha_grammar_k_parity_se(labelsP []int, labelsB []int, k int) (MCFG,
 
 	p := int_to_par(0, k)
 
-	writeLine("S(X0 E) :- Se(X0), Eps(E).",res)
-	writeLine("S(X0 E) :- S" + p + "(X0), Eps(E).",res)
+	writeLine("S(X0) :- Se(X0).",res)
+	writeLine("S(X0) :- S" + p + "(X0).",res)
 
 	//fmt.Println(*res)
 
 	return ParseNormalForm(strings.NewReader(*res))
 }
 
-func dyck_beta_grammar(labelsP []int, labelsB []int) (MCFG, error) {
+func DyckBetaGrammar(labelsP []int, labelsB []int) (MCFG, error) {
     var d = ""
     var res = &d
     writeLine(mk_brackets(labelsB), res)
@@ -201,7 +200,7 @@ func dyck_beta_grammar(labelsP []int, labelsB []int) (MCFG, error) {
     return ParseNormalForm(strings.NewReader(*res))
 }
 
-func dyck_beta_grammar_k_parity_se(labelsP []int, labelsB []int, k int) (MCFG, error) {
+func DyckBetaGrammarKParity(labelsP []int, labelsB []int, k int) (MCFG, error) {
 
 	var d = ""
 	var res = &d
@@ -217,7 +216,6 @@ func dyck_beta_grammar_k_parity_se(labelsP []int, labelsB []int, k int) (MCFG, e
 	writeLine(mk_brackets(labelsB),res)
 	writeLine("Se(eps).",res)
 	writeLine("Se(normal).",res)
-	writeLine("Eps(eps).",res)
 	//writeLine(mk_dummy_brackets("Si",labelsB),res)
 	emptyNum := 0
 	for _, val := range labelsP {
@@ -264,21 +262,19 @@ func dyck_beta_grammar_k_parity_se(labelsP []int, labelsB []int, k int) (MCFG, e
 
 	p := int_to_par(0, k)
 
-	writeLine("S(X0 E) :- Se(X0), Eps(E).",res)
-	writeLine("S(X0 E) :- S" + p + "(X0), Eps(E).",res)
+	writeLine("S(X0) :- Se(X0).",res)
+	writeLine("S(X0) :- S" + p + "(X0).",res)
 
 	//fmt.Println(*res)
 
 	return ParseNormalForm(strings.NewReader(*res))
 }
 
-func interleaved_dyck(labelsP []int, labelsB []int) (MCFG, error) {
+func InterleavedDyckGrammar(labelsP []int, labelsB []int) (MCFG, error) {
 	//fmt.Println(K,len(labelsP),len(labelsB))
 	var d = ""
 	var res = &d
 
-	writeLine(`Eps(eps).`,res)
-
 	writeLine(mk_parenthesis(labelsP),res)
 	writeLine(mk_brackets(labelsB),res)
 
@@ -296,12 +292,11 @@ func interleaved_dyck(labelsP []int, labelsB []int) (MCFG, error) {
 
 }
 
-func bracket_grammar(labelsP []int, labelsB []int) (MCFG, error) {
+func BracketGrammar(labelsP []int, labelsB []int) (MCFG, error) {
 	//fmt.Println(K,len(labelsP),len(labelsB))
 	var d = ""
 	var res = &d
 
-	writeLine("Eps(eps).",res)
 	writeLine("Sn(eps).",res)
 	writeLine("Sn(normal).",res)
 
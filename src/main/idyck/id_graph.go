@@ -1,4 +1,4 @@
-package main
+package idyck
 
 import (
 	"strconv"
@@ -14,11 +14,22 @@ func otherLabel (s string) string {
 	return string(sp) 
 }
 
-func parseDyckComponent (g *graph) ([]int, []int, *graph) {
+func ParseDyckComponent (g *Graph) ([]int, []int, *Graph) {
+	parId, braId, edges := DyckEdges(g.GetEdges())
+	parsedDyck := MakeGraph()
+	for _, e := range edges {
+		parsedDyck.AddEdge(e.From,e.To,e.Label)
+	}
+	return parId, braId, parsedDyck
+}
+
+// DyckEdges is ParseDyckComponent on a list of edges, e.g. of
+// ParseDotEdges
+func DyckEdges (edges []Edge) ([]int, []int, []Edge) {
 	seen := make(map[string]bool)
 	parId := []int{}
 	braId := []int{}
-	for _, e := range g.GetEdges() {
+	for _, e := range edges {
 		label := string(e.Label)
 		if len(label)>0 && label!="normal" && !seen[label] && seen[otherLabel(label)] {
 			idString := label[4:]
@@ -31,17 +42,17 @@ func parseDyckComponent (g *graph) ([]int, []int, *graph) {
 		}
 		seen[label] = true
 	}
-	parsedDyck := MakeGraph()
-	for _, e := range g.GetEdges() {
+	res := []Edge{}
+	for _, e := range edges {
 		label := string(e.Label)
 		if label=="normal" || (len(label)!=0 && seen[label] && seen[otherLabel(label)]) {
-			parsedDyck.AddEdge(e.From,e.To,e.Label)
+			res = append(res, e)
 		}
 	}
-	return parId, braId, parsedDyck
+	return parId, braId, res
 }
 
-func parseDyckComponentNaive (g *graph) ([]int, []int, *graph) {
+func parseDyckComponentNaive (g *Graph) ([]int, []int, *Graph) {
 	seen := make(map[string]bool)
 	parId := []int{}
 	braId := []int{}
@@ -94,7 +105,7 @@ func joinCondensateDyck(v Vertex, u Vertex, parent *map[Vertex]Vertex, weight *m
 	(*parent)[u] = v
 }
 
-func (g *graph) notCondensateDyck(underApprox [][]Vertex, c byte) (*graph, map[Vertex][]Vertex, map[Vertex]Vertex) {
+func (g *Graph) notCondensateDyck(underApprox [][]Vertex, c byte) (*Graph, map[Vertex][]Vertex, map[Vertex]Vertex) {
 
 
 	parent := make(map[Vertex]Vertex)
@@ -113,7 +124,7 @@ func (g *graph) notCondensateDyck(underApprox [][]Vertex, c byte) (*graph, map[V
 	return newGraph, findToVertex, parent
 }
 
-func (g *graph) condensateDyck(underApprox [][]Vertex, c byte) (*graph, map[Vertex][]Vertex, map[Vertex]Vertex) {
+func (g *Graph) condensateDyck(underApprox [][]Vertex, c byte) (*Graph, map[Vertex][]Vertex, map[Vertex]Vertex) {
 
 	parent := make(map[Vertex]Vertex)
 	weight := make(map[Vertex]int)
@@ -192,13 +203,13 @@ func (g *graph) condensateDyck(underApprox [][]Vertex, c byte) (*graph, map[Vert
 
 
 //return paths that can have form [s]
-func (g *graph) filterBracketPaths(paths []path) []path {
+func (g *Graph) filterBracketPaths(paths []Path) []Path {
 	comp, reach := g.findSccs()
-	ans := []path{}
+	ans := []Path{}
 	for _, currPath := range paths {
-		for _, outEdge := range g.OutEdges(currPath.start, "ob--0") {
-			for _, inEdge := range g.InEdges(currPath.end, "cb--0") {
-				if reach[[2]int{comp[outEdge],comp[inEdge]}] {
+		for _, outEdge := range g.OutEdges(currPath.Start, "ob--0") {
+			for _, inEdge := range g.InEdges(currPath.End, "cb--0") {
+				if reach.reaches(comp[outEdge],comp[inEdge]) {
 					ans = append(ans, currPath)
 				}
 			}
@@ -207,7 +218,7 @@ func (g *graph) filterBracketPaths(paths []path) []path {
 	return ans
 }
 
-func (g *graph) removeValueflowUnreachable() *graph {
+func (g *Graph) removeValueflowUnreachable() *Graph {
 
 	comp, reach := g.findSccs()
 	source := make(map[int]bool)
@@ -230,7 +241,7 @@ func (g *graph) removeValueflowUnreachable() *graph {
 		goodSource := false
 		goodSink := false
 		for src, _ := range source {
-			if reach[[2]int{src,comp[vertex]}] {
+			if reach.reaches(src,comp[vertex]) {
 				goodSource = true
 				break
 			}
@@ -239,7 +250,7 @@ func (g *graph) removeValueflowUnreachable() *graph {
 			continue
 		}
 		for snk, _ := range sink {
-			if reach[[2]int{comp[vertex],snk}] {
+			if reach.reaches(comp[vertex],snk) {
 				goodSink = true
 				break
 			}
@@ -259,7 +270,7 @@ func (g *graph) removeValueflowUnreachable() *graph {
 	return processed
 }
 
-func (g *graph) condensateValueflow() *graph {
+func (g *Graph) condensateValueflow() *Graph {
 
 	toAdd := []Edge{}
 	deleted := make(map[Vertex]bool)
@@ -346,8 +357,8 @@ func (g *graph) condensateValueflow() *graph {
 	return newGraph
 }
 
-func (g *graph) getAllPaths() []path {
-	paths := []path{}
+func (g *Graph) getAllPaths() []Path {
+	paths := []Path{}
 	components := g.splitComponents()
 
 	for _, comp := range components {
@@ -356,8 +367,8 @@ func (g *graph) getAllPaths() []path {
 
 		for u, _ := range comp.vertices {
 			for v, _ := range comp.vertices {
-				if u != v && reach[[2]int{scc[u],scc[v]}] {
-					makePath(u,v)
+				if u != v && reach.reaches(scc[u],scc[v]) {
+					paths = append(paths, makePath(u,v))
 				}
 			}
 		}
@@ -366,20 +377,20 @@ func (g *graph) getAllPaths() []path {
 	return paths
 }
 
-func (g *graph) graphReaches(u Vertex, v Vertex, component *map[Vertex]int, reaches *map[[2]int]bool) bool {
-	return (*reaches)[[2]int{(*component)[u],(*component)[v]}]
+func (g *Graph) graphReaches(u Vertex, v Vertex, component *map[Vertex]int, reaches *sccReachability) bool {
+	return reaches.reaches((*component)[u],(*component)[v])
 }
 
-func (g *graph) removeNotPath(overApprox []path) (*graph){
+func (g *Graph) RemoveNotPath(overApprox []Path) (*Graph){
 	pathMatrix := [][]Vertex{}
 	for _, path := range overApprox {
-		pathMatrix = append(pathMatrix, []Vertex{path.start,path.end})
+		pathMatrix = append(pathMatrix, []Vertex{path.Start,path.End})
 	}
 	return g.removeNotPathMatrix(&pathMatrix)
 }
 
 
-func (g *graph) removeNotPathMatrix(overApprox *[][]Vertex) (*graph){
+func (g *Graph) removeNotPathMatrix(overApprox *[][]Vertex) (*Graph){
 	if len(*overApprox) == len(g.vertices)*len(g.vertices) {
 		return g
 	}
@@ -401,7 +412,7 @@ func (g *graph) removeNotPathMatrix(overApprox *[][]Vertex) (*graph){
 			continue
 		}
 		for pair,_ := range overMap {
-			if reach[[2]int{pair[0],comp[edge.From]}] && reach[[2]int{comp[edge.To],pair[1]}] {
+			if reach.reaches(pair[0],comp[edge.From]) && reach.reaches(comp[edge.To],pair[1]) {
 				keep[[2]Vertex{edge.From,edge.To}] = true
 				break
 			}
@@ -417,7 +428,7 @@ func (g *graph) removeNotPathMatrix(overApprox *[][]Vertex) (*graph){
 	return processed
 }
 
-func (g *graph) reachablePairs(refinedPairs *[][]Vertex) (map[[2]Vertex]bool) {
+func (g *Graph) reachablePairs(refinedPairs *[][]Vertex) (map[[2]Vertex]bool) {
 	comp, reach := g.findSccs()
 	viable := map[[2]Vertex]bool{}
 
@@ -430,7 +441,7 @@ func (g *graph) reachablePairs(refinedPairs *[][]Vertex) (map[[2]Vertex]bool) {
 
 	for u, _ := range g.vertices {
 		for v, _ := range g.vertices {
-			if !reach[[2]int{comp[u],comp[v]}] {
+			if !reach.reaches(comp[u],comp[v]) {
 				continue
 			}
 			if overMap[[2]int{comp[u],comp[v]}] {
@@ -439,7 +450,7 @@ func (g *graph) reachablePairs(refinedPairs *[][]Vertex) (map[[2]Vertex]bool) {
 			}
 			for pair, _ := range overMap {
 				//fmt.Println("overmap ", pair[0], pair[1], comp[u], comp[v])
-				if reach[[2]int{pair[0],comp[u]}] && reach[[2]int{comp[v],pair[1]}] {
+				if reach.reaches(pair[0],comp[u]) && reach.reaches(comp[v],pair[1]) {
 					viable[[2]Vertex{u,v}] = true
 					break
 				}
@@ -450,7 +461,7 @@ func (g *graph) reachablePairs(refinedPairs *[][]Vertex) (map[[2]Vertex]bool) {
 	return viable
 }
 
-func (g *graph) valueflowTransformation() (*graph) {
+func (g *Graph) valueflowTransformation() (*Graph) {
 
 	newGraph := MakeGraph()
 
@@ -482,12 +493,12 @@ func pathsValueflowTransformation(overApprox *[][]Vertex) ([][]Vertex) {
 	return newOverApprox
 }
 
-func filterValueflowPaths(paths []path) []path{
-	ans := []path{}
+func filterValueflowPaths(paths []Path) []Path{
+	ans := []Path{}
 	for _, vf := range paths {
-		if vf.start%3 == 0 && vf.end%3 == 2 {
-			ansPath := makePath(vf.start/3,vf.end/3)
-			if ansPath.start != ansPath.end {
+		if vf.Start%3 == 0 && vf.End%3 == 2 {
+			ansPath := makePath(vf.Start/3,vf.End/3)
+			if ansPath.Start != ansPath.End {
 				ans = append(ans, ansPath)
 			}
 		}
@@ -495,29 +506,29 @@ func filterValueflowPaths(paths []path) []path{
 	return ans
 }
 
-func filterUsedEdges(sDerivations *[]path) (map[Edge]bool) {
+func (a *Analyzer) filterUsedEdges(sDerivations *[]Path) (map[Edge]bool) {
 
 	//fmt.Println("finding used edges")
 
-	newDeriToEdge := map[uint64][]Edge{}
-	newDeriToDeri := map[[2]uint64]bool{}
+	newDeriToEdge := map[derivationID][]Edge{}
+	newDeriToDeri := map[[2]derivationID]bool{}
 
-	deriToDeriList := map[uint64][]uint64{}
-	for deriEdge , _ := range deriToDeri {
+	deriToDeriList := map[derivationID][]derivationID{}
+	for deriEdge , _ := range a.prov.deriToDeri {
 		deriToDeriList[deriEdge[0]]=append(deriToDeriList[deriEdge[0]], deriEdge[1])
 	}
 
-	seenDeri := map[uint64]bool{}
+	seenDeri := map[derivationID]bool{}
 	seenEdge := map[Edge]bool{}
 
-	var recursive func(curr uint64) 
-	recursive = func(curr uint64) {
+	var recursive func(curr derivationID) 
+	recursive = func(curr derivationID) {
 		if seenDeri[curr] {
 			return
 		}
 		seenDeri[curr]= true
 		//fmt.Println("printing one deri to edge")
-		for _, edge := range deriToEdge[curr] {
+		for _, edge := range a.prov.deriToEdge[curr] {
 			if len(edge.Label) == 0 {
 				continue
 			}
@@ -526,22 +537,19 @@ func filterUsedEdges(sDerivations *[]path) (map[Edge]bool) {
 		}
 		//fmt.Println("done")
 		for _, deri := range deriToDeriList[curr] {
-			newDeriToDeri[[2]uint64{curr,deri}] = true
+			newDeriToDeri[[2]derivationID{curr,deri}] = true
 			//fmt.Println("usedEdges", curr, "called", deri)
 			recursive(deri)
 		}
 	}
 
 	for _, s := range (*sDerivations) {
-		targetDerivation := derivation{
-			name:       "S",
-			segments:   []path{s},
+		if id, ok := a.prov.startID(s); ok {
+			recursive(id)
 		}
-		recursive(targetDerivation.Hash())
 	}
 	//fmt.Println("filtered edges ", len(deriToEdge),len( newDeriToEdge) )
-	deriToEdge = newDeriToEdge
-	deriToDeri = newDeriToDeri
+	a.prov = &provenance{ids: a.prov.ids, deriToEdge: newDeriToEdge, deriToDeri: newDeriToDeri}
 	//fmt.Println("filtered deri ", len(deriToDeri),len( newDeriToDeri) )
 
 	//fmt.Println("found")
@@ -551,26 +559,26 @@ func filterUsedEdges(sDerivations *[]path) (map[Edge]bool) {
 }
 
 //not change parity for normal edges 
-func usedEdges(sDerivations *[]path) (map[Edge]bool) {
+func (a *Analyzer) usedEdges(sDerivations *[]Path) (map[Edge]bool) {
 
 	//fmt.Println("finding used edges")
 
-	seenDeri := map[uint64]bool{}
+	seenDeri := map[derivationID]bool{}
 	seenEdge := map[Edge]bool{}
 
-	deriToDeriList := map[uint64][]uint64{}
-	for deriEdge , _ := range deriToDeri {
+	deriToDeriList := map[derivationID][]derivationID{}
+	for deriEdge , _ := range a.prov.deriToDeri {
 		deriToDeriList[deriEdge[0]]=append(deriToDeriList[deriEdge[0]], deriEdge[1])
 	}
 
-	var recursive func(curr uint64) 
-	recursive = func(curr uint64) {
+	var recursive func(curr derivationID) 
+	recursive = func(curr derivationID) {
 		if seenDeri[curr] {
 			return
 		}
 		seenDeri[curr]= true
 		//fmt.Println("printing one deri to edge")
-		for _, edge := range deriToEdge[curr] {
+		for _, edge := range a.prov.deriToEdge[curr] {
 			if len(edge.Label) == 0 {
 				continue
 			}
@@ -585,14 +593,12 @@ func usedEdges(sDerivations *[]path) (map[Edge]bool) {
 	}
 
 	for _, s := range (*sDerivations) {
-		if s.start == s.end {
+		if s.Start == s.End {
 			continue
 		}
-		targetDerivation := derivation{
-			name:       "S",
-			segments:   []path{s},
+		if id, ok := a.prov.startID(s); ok {
+			recursive(id)
 		}
-		recursive(targetDerivation.Hash())
 	}
 
 	return seenEdge
@@ -601,12 +607,12 @@ func usedEdges(sDerivations *[]path) (map[Edge]bool) {
 
 
 // automaton depends on benchmark
-func (g *graph) multiplyByAutomaton(labelsB []int) (*graph) {
+func (g *Graph) multiplyByAutomaton(labelsB []int, valueflow bool) (*Graph) {
 
 	newGraph := MakeGraph()
 
 	//automata for one bracket only and takes care of s = [ s'] condition
-	if directoryInput == "valueflow" {
+	if valueflow {
 		k := 6
 		for _, e := range g.GetEdges() {
 			if e.From == e.To && len(e.Label)==0 {
@@ -679,19 +685,34 @@ func (g *graph) multiplyByAutomaton(labelsB []int) (*graph) {
 
 }
 
-func filterAutomatonPaths(paths []path, labelsB []int) []path{
+// automatonVertices returns the vertices of multiplyByAutomaton that
+// filterAutomatonPaths takes for a start at v, or an end if sink
+func automatonVertices(v Vertex, labelsB []int, valueflow bool, sink bool) []Vertex {
+	s := 0
+	k := len(labelsB)+2
+	if valueflow {
+		s = 2
+		k = 6
+	}
+	if !sink {
+		return []Vertex{Vertex(k*int(v))}
+	}
+	return []Vertex{Vertex(k*int(v)+s), Vertex(k*int(v)+k-1)}
+}
+
+func filterAutomatonPaths(paths []Path, labelsB []int, valueflow bool) []Path{
 	s := 0
 	k := len(labelsB)+2
-	if directoryInput == "valueflow" {
+	if valueflow {
 		s = 2
 		k = 6
 	}
-	ans := []path{}
-	seen := make(map[path]bool)
+	ans := []Path{}
+	seen := make(map[Path]bool)
 	for _, vf := range paths {
-		if int(vf.start)%k == 0 && (int(vf.end)%k == s || int(vf.end)%k == k-1){
-			ansPath := makePath(Vertex(int(vf.start)/k),Vertex(int(vf.end)/k))
-			if ansPath.start != ansPath.end && !seen[ansPath] {
+		if int(vf.Start)%k == 0 && (int(vf.End)%k == s || int(vf.End)%k == k-1){
+			ansPath := makePath(Vertex(int(vf.Start)/k),Vertex(int(vf.End)/k))
+			if ansPath.Start != ansPath.End && !seen[ansPath] {
 				seen[ansPath] = true
 				ans = append(ans, ansPath)
 			}
@@ -0,0 +1,138 @@
+package idyck
+
+import (
+	"context"
+	"fmt"
+)
+
+// Delta is a change of a graph between two versions of a program.
+type Delta struct {
+	Added   []Edge
+	Removed []Edge
+}
+
+// Apply returns g without the removed edges and with the added ones, g is
+// not changed. An edge both removed and added is kept. Vertices left
+// without edges are dropped, as if the new graph was read from a file.
+func (d Delta) Apply(g *Graph) *Graph {
+	removed := map[Edge]bool{}
+	for _, e := range d.Removed {
+		removed[e] = true
+	}
+	res := MakeGraph()
+	exists := map[Edge]bool{}
+	add := func(e Edge) {
+		if exists[e] || e.From == e.To && len(e.Label) == 0 {
+			return
+		}
+		exists[e] = true
+		res.AddEdge(e.From, e.To, e.Label)
+	}
+	for _, e := range g.edgeList {
+		if !removed[e] {
+			add(e)
+		}
+	}
+	for _, e := range d.Added {
+		add(e)
+	}
+	return res
+}
+
+// affected returns the vertices whose pairs can change with d: the
+// endpoints of the changed edges and the components of g containing them.
+// The components of the new graph with one of these vertices contain only
+// such vertices, the other ones are components of g as they were.
+func (d Delta) affected(g *Graph) map[Vertex]bool {
+	res := map[Vertex]bool{}
+	for _, edges := range [][]Edge{d.Added, d.Removed} {
+		for _, e := range edges {
+			res[e.From], res[e.To] = true, true
+		}
+	}
+	for _, comp := range g.splitComponents() {
+		touched := false
+		for v := range comp.vertices {
+			if res[v] {
+				touched = true
+				break
+			}
+		}
+		if !touched {
+			continue
+		}
+		for v := range comp.vertices {
+			res[v] = true
+		}
+	}
+	return res
+}
+
+// Update reanalyzes g changed by delta, given the result prev of a finished
+// run of the same stages, with the same pruning, on g. Every stage finds
+// the pairs of a connected component from the edges of the component
+// alone, so only the components of the new graph that contain a changed
+// edge, or a vertex of a component of g that did, run the stages again;
+// the pairs of the others are kept from prev.
+//
+// If delta only adds edges, no stage loses a pair it found in prev. The
+// on-demand refinement resumes from prev: it keeps its pairs of prev
+// without refining them again and refines only the other pairs. The other
+// stages run again on the changed components, as the engine would need
+// the derivations of the previous run to resume, not just its pairs. If
+// delta removes edges, pairs of prev can be lost and all stages run again
+// on the changed components.
+//
+// The new graph is returned with a result equal to the one of RunContext on
+// it, except for the statistics, which are those of the stages on the
+// changed components. If ctx is done the stages that finished are returned
+// as by RunContext.
+func (a *Analyzer) Update(ctx context.Context, g *Graph, prev Result, delta Delta) (*Graph, Result, error) {
+	next := delta.Apply(g)
+	if prev.Interrupted != "" {
+		return next, Result{}, fmt.Errorf("update: stage %s of the previous result was interrupted", prev.Interrupted)
+	}
+	if fmt.Sprint(prev.Stages) != fmt.Sprint(a.stages) {
+		return next, Result{}, fmt.Errorf("update: the previous result has the stages %v, not %v", prev.Stages, a.stages)
+	}
+	if len(prev.Stats) != len(prev.Stages) {
+		return next, Result{}, fmt.Errorf("update: the previous result has no statistics of its stages")
+	}
+	prevPrune := []Stage{}
+	for _, stats := range prev.Stats {
+		if stats.Pruned {
+			prevPrune = append(prevPrune, stats.Stage)
+		}
+	}
+	if prune := a.Config().Prune; fmt.Sprint(prevPrune) != fmt.Sprint(prune) {
+		return next, Result{}, fmt.Errorf("update: the previous result pruned with %v, not %v", prevPrune, prune)
+	}
+
+	affected := delta.affected(g)
+	changed := MakeGraph()
+	for _, e := range next.edgeList {
+		if affected[e.From] && (e.From != e.To || len(e.Label) > 0) {
+			changed.AddEdge(e.From, e.To, e.Label)
+		}
+	}
+
+	if len(delta.Removed) == 0 {
+		a.kept = map[Path]bool{}
+		for _, p := range prev.OnDemand {
+			if affected[p.Start] {
+				a.kept[p] = true
+			}
+		}
+		defer func() { a.kept = nil }()
+	}
+
+	res, err := a.RunContext(ctx, changed)
+	res.Vertices, res.Edges = next.NumVertices(), next.NumEdges()
+	for i, stage := range res.Stages {
+		kept := filterPaths(prev.Paths(stage), func(p Path) bool { return !affected[p.Start] })
+		*res.field(stage) = append(kept, res.Paths(stage)...)
+		res.Stats[i].Pairs = len(res.Paths(stage))
+	}
+	res.sort()
+	return next, res, err
+}
@@ -0,0 +1,108 @@
+package idyck
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// randomEdges returns n labelled edges between the vertices 0 to vertices-1
+func randomEdges(rng *rand.Rand, vertices int, n int, labels []Label) []Edge {
+	edges := []Edge{}
+	for i := 0; i < n; i++ {
+		edges = append(edges, Edge{From: Vertex(rng.Intn(vertices)), To: Vertex(rng.Intn(vertices)), Label: labels[rng.Intn(len(labels))]})
+	}
+	return edges
+}
+
+func labelledEdges(g *Graph) []Edge {
+	edges := []Edge{}
+	for _, e := range g.GetEdges() {
+		if e.From != e.To || len(e.Label) > 0 {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// TestUpdate checks that Update gives the pairs of a run on the changed
+// graph, for added, removed and both added and removed edges
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	//refinements of Update with only added edges, and with the same edges
+	//also removed and added again, which refines everything again
+	resumed, again := 0, 0
+	for i := 0; i < 12; i++ {
+		g := Delta{Added: randomEdges(rng, 8, 16, _dyckTestLabels)}.Apply(MakeGraph())
+		edges := labelledEdges(g)
+		added := randomEdges(rng, 8, 3, _dyckTestLabels)
+		removed := []Edge{edges[rng.Intn(len(edges))], edges[rng.Intn(len(edges))]}
+		prev, err := NewAnalyzer(Config{Parallelism: 1}).RunContext(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, delta := range map[string]Delta{
+			"added":   {Added: added},
+			"removed": {Removed: removed},
+			"both":    {Added: added, Removed: removed},
+			"again":   {Added: added, Removed: added[:1]},
+		} {
+			a := NewAnalyzer(Config{Parallelism: 1})
+			next, got, err := a.Update(ctx, g, prev, delta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := NewAnalyzer(Config{Parallelism: 1}).RunContext(ctx, next)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, stage := range want.Stages {
+				if fmt.Sprint(got.Paths(stage)) != fmt.Sprint(want.Paths(stage)) {
+					t.Errorf("graph %d, %s edges, %s: got %v, want %v", i, name, stage, got.Paths(stage), want.Paths(stage))
+				}
+			}
+			switch name {
+			case "added":
+				resumed += a.queries
+			case "again":
+				again += a.queries
+			}
+		}
+	}
+	if resumed >= again {
+		t.Errorf("with added edges only %d pairs were refined, %d else", resumed, again)
+	}
+}
+
+func TestUpdateChecksPrevious(t *testing.T) {
+	ctx := context.Background()
+	g := Delta{Added: randomEdges(rand.New(rand.NewSource(1)), 6, 12, _dyckTestLabels)}.Apply(MakeGraph())
+	prev, err := NewAnalyzer(Config{}).RunContext(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interrupted := prev
+	interrupted.Interrupted = StageOnDemand
+	noStats := prev
+	noStats.Stats = nil
+
+	for _, c := range []struct {
+		name   string
+		config Config
+		prev   Result
+		err    string
+	}{
+		{"interrupted", Config{}, interrupted, "update: stage on-demand of the previous result was interrupted"},
+		{"stages", Config{Stages: []Stage{StageUnderapproximation}}, prev, "update: the previous result has the stages"},
+		{"prune", Config{Prune: []Stage{StageIntersection}}, prev, "update: the previous result pruned with [intersection mutual-refinement stronger-grammar], not [intersection]"},
+		{"stats", Config{}, noStats, "update: the previous result has no statistics of its stages"},
+	} {
+		_, _, err := NewAnalyzer(c.config).Update(ctx, g, c.prev, Delta{})
+		if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+			t.Errorf("%s: got %v, want %s", c.name, err, c.err)
+		}
+	}
+}
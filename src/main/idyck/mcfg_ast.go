@@ -0,0 +1,213 @@
+package idyck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Abstract syntax of the .mcfg surface syntax (cf. mcfg_data.py). A parsed
+// grammar can be semantically incorrect and is not yet in normal form.
+
+// Pos is a 1-based line and column in a .mcfg source.
+type Pos struct {
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+type SymbolKind int
+
+const (
+	Terminal SymbolKind = iota
+	Variable            //names starting with a capital
+	Epsilon             //the terminal "eps", the empty word
+)
+
+type Symbol struct {
+	Kind SymbolKind
+	Name string
+	Pos  Pos
+}
+
+// Word is a sequence of symbols separated by whitespace, an argument of a head
+type Word []Symbol
+
+// HeadAST is Name(w_1, ..., w_n)
+type HeadAST struct {
+	Name string
+	Args []Word
+	Pos  Pos
+}
+
+// BodyAST is Name(x_1, ..., x_n), each x_i should be a variable
+type BodyAST struct {
+	Name string
+	Args []Symbol
+	Pos  Pos
+}
+
+// RuleAST is Head :- Body_1, ..., Body_n.
+type RuleAST struct {
+	Head HeadAST
+	Body []BodyAST
+	Pos  Pos
+}
+
+type GrammarAST struct {
+	Rules []RuleAST
+}
+
+func symbolKind(name string) SymbolKind {
+	if isVar(name) {
+		return Variable
+	}
+	if name == _epsilonParseLabel {
+		return Epsilon
+	}
+	return Terminal
+}
+
+func (w Word) String() string {
+	names := []string{}
+	for _, s := range w {
+		names = append(names, s.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func (h HeadAST) String() string {
+	words := []string{}
+	for _, w := range h.Args {
+		words = append(words, w.String())
+	}
+	return fmt.Sprintf("%s(%s)", h.Name, strings.Join(words, ", "))
+}
+
+func (b BodyAST) String() string {
+	names := []string{}
+	for _, s := range b.Args {
+		names = append(names, s.Name)
+	}
+	return fmt.Sprintf("%s(%s)", b.Name, strings.Join(names, ", "))
+}
+
+func (r RuleAST) String() string {
+	if len(r.Body) == 0 {
+		return fmt.Sprintf("%s.", r.Head)
+	}
+	body := []string{}
+	for _, b := range r.Body {
+		body = append(body, b.String())
+	}
+	return fmt.Sprintf("%s :- %s.", r.Head, strings.Join(body, ", "))
+}
+
+func (g *GrammarAST) String() string {
+	rules := []string{}
+	for _, r := range g.Rules {
+		rules = append(rules, r.String())
+	}
+	return strings.Join(rules, "\n")
+}
+
+// MCFG validates the grammar and transforms it to normal form. Deleting,
+// permuting and high rank rules are transformed first.
+func (g *GrammarAST) MCFG() (MCFG, error) {
+	if err := g.Validate(); err != nil {
+		return MCFG{}, err
+	}
+
+	rules := g.lower()
+
+	dim, err := mcfgDimensions(rules)
+	if err != nil {
+		return MCFG{}, err
+	}
+
+	rules, dim = prepareRules(rules, dim)
+
+	mcfg := makeEmptyMCFG()
+	for _, rule := range normalizeRules(rules, dim) {
+		parseRule(mcfg, rule)
+	}
+
+	return *mcfg, mcfg.Validate()
+}
+
+// lower drops positions and symbol kinds, keeping the names only
+func (g *GrammarAST) lower() []mcfgRule {
+	rules := []mcfgRule{}
+	for _, r := range g.Rules {
+		rule := mcfgRule{
+			head: mcfgHead{nterm: r.Head.Name, args: [][]string{}},
+			body: []mcfgBody{},
+		}
+		for _, w := range r.Head.Args {
+			word := []string{}
+			for _, s := range w {
+				word = append(word, s.Name)
+			}
+			rule.head.args = append(rule.head.args, word)
+		}
+		for _, b := range r.Body {
+			atom := mcfgBody{nterm: b.Name, args: []string{}}
+			for _, s := range b.Args {
+				atom.args = append(atom.args, s.Name)
+			}
+			rule.body = append(rule.body, atom)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Rules as handled by the normal form transformation: names only.
+
+// nterm(w_1, ..., w_n) where each w_i is a word of terminals and variables
+type mcfgHead struct {
+	nterm string
+	args  [][]string
+}
+
+// nterm(x_1, ..., x_n) where each x_i is a variable
+type mcfgBody struct {
+	nterm string
+	args  []string
+}
+
+// head :- body_1, ..., body_n.
+type mcfgRule struct {
+	head mcfgHead
+	body []mcfgBody
+}
+
+func (h mcfgHead) String() string {
+	words := []string{}
+	for _, w := range h.args {
+		words = append(words, strings.Join(w, " "))
+	}
+	return fmt.Sprintf("%s(%s)", h.nterm, strings.Join(words, ", "))
+}
+
+func (b mcfgBody) String() string {
+	return fmt.Sprintf("%s(%s)", b.nterm, strings.Join(b.args, ", "))
+}
+
+func (r mcfgRule) String() string {
+	if len(r.body) == 0 {
+		return fmt.Sprintf("%s.", r.head)
+	}
+	body := []string{}
+	for _, b := range r.body {
+		body = append(body, b.String())
+	}
+	return fmt.Sprintf("%s :- %s.", r.head, strings.Join(body, ", "))
+}
+
+// variables start with a capital, everything else is a terminal
+func isVar(x string) bool {
+	return len(x) > 0 && 'A' <= x[0] && x[0] <= 'Z'
+}
@@ -0,0 +1,73 @@
+package idyck
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestMemoKeyExact(t *testing.T) {
+	a := NewAnalyzer(Config{})
+	a.clearMaps()
+	//formatted as "1->2[a] 3->4[b]" both, which the FNV key could not tell apart
+	one := MakeGraph()
+	one.AddEdge(1, 2, "a] 3->4[b")
+	two := MakeGraph()
+	two.AddEdge(3, 4, "b")
+	two.AddEdge(1, 2, "a")
+	twoAgain := MakeGraph()
+	twoAgain.AddEdge(1, 2, "a")
+	twoAgain.AddEdge(3, 4, "b")
+	twoAgain.AddEdge(3, 4, "b")
+
+	if a.newMemoKey(one, nil) == a.newMemoKey(two, nil) {
+		t.Errorf("different edge sets share a memo key")
+	}
+	if a.newMemoKey(two, nil) != a.newMemoKey(twoAgain, nil) {
+		t.Errorf("the same edge set has different memo keys")
+	}
+	goal := Path{Start: 1, End: 2}
+	if a.newMemoKey(two, &goal) == a.newMemoKey(two, nil) {
+		t.Errorf("a goal-directed run shares the memo key of the full run")
+	}
+}
+
+// TestDerivationKeyExact checks that two derivations have the same key iff
+// they have the same nonterminal and segments, also beyond _keySegments
+// where the segments are interned. Each nonterminal has its dimension.
+func TestDerivationKeyExact(t *testing.T) {
+	m, err := InterleavedDyckGrammar([]int{0}, []int{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grammar, err := CompileGrammar(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newReachCSR(NewCSRGraph(MakeGraph()), grammar, false)
+
+	dims := []int{1, 2, 3, 5}
+	rng := rand.New(rand.NewSource(1))
+	keys := map[derivationKey]string{}
+	derivations := map[string]derivationKey{}
+	for i := 0; i < 20000; i++ {
+		name := int32(rng.Intn(len(dims)))
+		segs := segments{}
+		for j := 0; j < dims[name]; j++ {
+			segs = append(segs, Path{Start: Vertex(rng.Intn(3)), End: Vertex(rng.Intn(3))})
+		}
+		d := fmt.Sprint(name, segs)
+		key := r.segmentsKey(name, segs)
+		if other, ok := keys[key]; ok && other != d {
+			t.Fatalf("%s and %s share a key", d, other)
+		}
+		if other, ok := derivations[d]; ok && other != key {
+			t.Fatalf("%s has two keys", d)
+		}
+		keys[key] = d
+		derivations[d] = key
+	}
+	if len(keys) != len(derivations) {
+		t.Errorf("%d keys for %d derivations", len(keys), len(derivations))
+	}
+}
@@ -0,0 +1,457 @@
+package idyck
+
+import (
+	"fmt"
+)
+
+// Transformation to normal form [Pavlogiannis, van de Pol], ported from
+// mcfg_normal.py. dim maps every nonterminal to its dimension and is
+// extended with the fresh nonterminals introduced by the steps.
+
+func normalizeRules(mcfg []mcfgRule, dim map[string]int) []mcfgRule {
+	mcfg = normalStep1(mcfg, dim)
+	mcfg = normalStep2(mcfg, dim)
+	mcfg = normalStep3(mcfg, dim)
+	mcfg = normalStep4(mcfg, dim)
+	mcfg = normalStep5(mcfg, dim)
+	mcfg = normalStep6(mcfg, dim)
+	mcfg = normalStep7(mcfg, dim)
+	return mcfg
+}
+
+// newNterm returns nterm if it is fresh, and otherwise the first fresh
+// nterm without trailing digits followed by an index
+func newNterm(dim map[string]int, arity int, nterm string) string {
+	if _, ok := dim[nterm]; !ok {
+		dim[nterm] = arity
+		return nterm
+	}
+	for len(nterm) > 0 && '0' <= nterm[len(nterm)-1] && nterm[len(nterm)-1] <= '9' {
+		nterm = nterm[:len(nterm)-1]
+	}
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s%d", nterm, i)
+		if _, ok := dim[name]; !ok {
+			dim[name] = arity
+			return name
+		}
+	}
+}
+
+// Z0, ..., Z(n-1)
+func zVars(n int) []string {
+	res := make([]string, n)
+	for i := range res {
+		res[i] = fmt.Sprintf("Z%d", i)
+	}
+	return res
+}
+
+// [[x] for x in vars]
+func varWords(vars []string) [][]string {
+	res := make([][]string, len(vars))
+	for i, x := range vars {
+		res[i] = []string{x}
+	}
+	return res
+}
+
+func concatWords(parts ...[][]string) [][]string {
+	res := [][]string{}
+	for _, part := range parts {
+		res = append(res, part...)
+	}
+	return res
+}
+
+func concatSymbols(parts ...[]string) []string {
+	res := []string{}
+	for _, part := range parts {
+		res = append(res, part...)
+	}
+	return res
+}
+
+// python-style slicing, clamped to the bounds of s
+func clampSlice[T any](s []T, from int, to int) []T {
+	if to > len(s) {
+		to = len(s)
+	}
+	if from > to {
+		return []T{}
+	}
+	return s[from:to]
+}
+
+func copyBodies(body []mcfgBody) []mcfgBody {
+	return append([]mcfgBody{}, body...)
+}
+
+// rules are popped from the end of the worklist, as in mcfg_normal.py
+func popRule(mcfg *[]mcfgRule) mcfgRule {
+	rule := (*mcfg)[len(*mcfg)-1]
+	*mcfg = (*mcfg)[:len(*mcfg)-1]
+	return rule
+}
+
+// STEP 1: A(w1, ..., wn). becomes A1(w1). and A(X, w2, ..., wn) :- A1(X).
+func normalStep1(mcfg []mcfgRule, dim map[string]int) []mcfgRule {
+	result := []mcfgRule{}
+	for _, rule := range mcfg {
+		if len(rule.body) > 0 || len(rule.head.args) <= 1 {
+			result = append(result, rule)
+			continue
+		}
+		A := rule.head.nterm
+		w1, ws := rule.head.args[0], rule.head.args[1:]
+		A1 := newNterm(dim, 1, A)
+		result = append(result, mcfgRule{head: mcfgHead{A1, [][]string{w1}}, body: []mcfgBody{}})
+		result = append(result, mcfgRule{
+			head: mcfgHead{A, concatWords([][]string{{"X"}}, ws)},
+			body: []mcfgBody{{A1, []string{"X"}}},
+		})
+	}
+	return result
+}
+
+// STEP 2: A(a w). becomes A1(a). and A(X w) :- A1(X).
+func normalStep2(mcfg []mcfgRule, dim map[string]int) []mcfgRule {
+	result := []mcfgRule{}
+	for _, rule := range mcfg {
+		if len(rule.body) > 0 || len(rule.head.args) > 1 || len(rule.head.args[0]) == 1 {
+			result = append(result, rule)
+			continue
+		}
+		A := rule.head.nterm
+		a, w1 := rule.head.args[0][0], rule.head.args[0][1:]
+		A1 := newNterm(dim, 1, A)
+		result = append(result, mcfgRule{head: mcfgHead{A1, [][]string{{a}}}, body: []mcfgBody{}})
+		result = append(result, mcfgRule{
+			head: mcfgHead{A, [][]string{concatSymbols([]string{"X"}, w1)}},
+			body: []mcfgBody{{A1, []string{"X"}}},
+		})
+	}
+	return result
+}
+
+// findTerminalSegment returns (i, j, k): the first argument i with a maximal
+// non-variable part [j:k], or (len(args), 0, 0)
+func findTerminalSegment(args [][]string) (int, int, int) {
+	for i, w := range args {
+		for j := range w {
+			if isVar(w[j]) {
+				continue
+			}
+			for k := j + 1; k < len(w); k++ {
+				if isVar(w[k]) {
+					return i, j, k
+				}
+			}
+			return i, j, len(w)
+		}
+	}
+	return len(args), 0, 0
+}
+
+// findRHS returns the position (atom, argument) of variable x in body
+func findRHS(x string, body []mcfgBody) (int, int) {
+	for l, atom := range body {
+		for m, arg := range atom.args {
+			if arg == x {
+				return l, m
+			}
+		}
+	}
+	panic(fmt.Sprintf("variable %s does not occur in the body", x))
+}
+
+// STEP 3: move terminals out of rules with more than one body atom
+func normalStep3(mcfg []mcfgRule, dim map[string]int) []mcfgRule {
+	result := []mcfgRule{}
+	mcfg = append([]mcfgRule{}, mcfg...)
+	for len(mcfg) > 0 {
+		rule := popRule(&mcfg)
+		head := rule.head
+		body := rule.body
+		if len(body) <= 1 {
+			result = append(result, rule)
+			continue
+		}
+		A0x := head.args
+		k0 := len(A0x)
+		i, j, k := findTerminalSegment(A0x)
+		if i == k0 { //only variables
+			result = append(result, rule)
+			continue
+		}
+		//i-th argument contains a maximal terminal word w=si[j:k]
+		A0 := head.nterm
+		si := A0x[i]
+		w := si[j:k]
+		if j == 0 && k == len(si) { //si = w
+			A1 := newNterm(dim, k0-1, A0)
+			X1 := zVars(k0)
+			X2 := varWords(X1)
+			head1 := mcfgHead{A0, concatWords(X2[0:i], [][]string{w}, X2[i+1:k0])}
+			body1 := mcfgBody{A1, concatSymbols(X1[0:i], X1[i+1:k0])}
+			result = append(result, mcfgRule{head1, []mcfgBody{body1}})
+			head2 := mcfgHead{A1, concatWords(A0x[0:i], A0x[i+1:k0])}
+			mcfg = append(mcfg, mcfgRule{head2, body})
+		} else if j == 0 { //si = w x s2
+			x := si[k]
+			s2 := si[k+1:]
+			l, m := findRHS(x, body)
+			atom := body[l]
+			Alx := atom.args
+			Al1 := newNterm(dim, len(Alx), A0)
+			newargs1 := varWords(Alx)
+			newargs1[m] = concatSymbols(w, []string{Alx[m]})
+			result = append(result, mcfgRule{mcfgHead{Al1, newargs1}, []mcfgBody{atom}})
+			newargs2 := concatWords(A0x)
+			newargs2[i] = concatSymbols([]string{x}, s2)
+			body2 := copyBodies(body)
+			body2[l] = mcfgBody{Al1, body[l].args}
+			mcfg = append(mcfg, mcfgRule{mcfgHead{A0, newargs2}, body2})
+		} else { //si = s1 x w s2
+			x := si[j-1]
+			s1 := si[:j-1]
+			s2 := si[k:]
+			el, m := findRHS(x, body)
+			atom := body[el]
+			Alx := atom.args
+			Al1 := newNterm(dim, len(Alx), A0)
+			head1 := mcfgHead{Al1, concatWords(varWords(Alx[:m]), [][]string{concatSymbols([]string{Alx[m]}, w)}, varWords(Alx[m+1:]))}
+			result = append(result, mcfgRule{head1, []mcfgBody{atom}})
+			head2 := mcfgHead{A0, concatWords(A0x[:i], [][]string{concatSymbols(s1, []string{x}, s2)}, A0x[i+1:])}
+			body2 := copyBodies(body)
+			body2[el] = mcfgBody{Al1, Alx}
+			mcfg = append(mcfg, mcfgRule{head2, body2})
+		}
+	}
+	return result
+}
+
+// findAllTerminal returns the argument found by findTerminalSegment if it
+// consists of terminals only, and len(args) otherwise
+func findAllTerminal(args [][]string) int {
+	i, j, k := findTerminalSegment(args)
+	if i < len(args) && j == 0 && k == len(args[i]) {
+		return i
+	}
+	return len(args)
+}
+
+// findNonVar returns the first argument that is not a single variable
+func findNonVar(args [][]string) int {
+	for i, w := range args {
+		if !(len(w) == 1 && isVar(w[0])) {
+			return i
+		}
+	}
+	return len(args)
+}
+
+// STEP 4: split off a terminal argument if other arguments are not variables
+func normalStep4(mcfg []mcfgRule, dim map[string]int) []mcfgRule {
+	result := []mcfgRule{}
+	mcfg = append([]mcfgRule{}, mcfg...)
+	for len(mcfg) > 0 {
+		rule := popRule(&mcfg)
+		head := rule.head
+		body := rule.body
+		if len(body) != 1 {
+			result = append(result, rule)
+			continue
+		}
+		A0x := head.args
+		k0 := len(A0x)
+		i := findAllTerminal(A0x)
+		if i == k0 { //no terminal argument found
+			result = append(result, rule)
+			continue
+		}
+		j := findNonVar(concatWords(A0x[:i], A0x[i+1:]))
+		if j == k0-1 { //no other non-var argument found
+			result = append(result, rule)
+			continue
+		}
+		A0 := head.nterm
+		A1 := newNterm(dim, k0-1, A0)
+		X1 := zVars(k0 - 1)
+		X2 := varWords(X1)
+		head1 := mcfgHead{A0, concatWords(X2[0:i], [][]string{A0x[i]}, X2[i:k0-1])}
+		result = append(result, mcfgRule{head1, []mcfgBody{{A1, X1}}})
+		head2 := mcfgHead{A1, concatWords(A0x[0:i], A0x[i+1:k0])}
+		mcfg = append(mcfg, mcfgRule{head2, body})
+	}
+	return result
+}
+
+// findTwoVars finds an argument of length > min with two variables
+func findTwoVars(args [][]string, min int) (int, int, int) {
+	for i, atom := range args {
+		if len(atom) <= min {
+			continue
+		}
+		for j := range atom {
+			if !isVar(atom[j]) {
+				continue
+			}
+			for k := j + 1; k < len(atom); k++ {
+				if isVar(atom[k]) {
+					return i, j, k
+				}
+			}
+		}
+	}
+	return len(args), 0, 0
+}
+
+// STEP 5: split arguments of length > 2 containing two variables
+func normalStep5(mcfg []mcfgRule, dim map[string]int) []mcfgRule {
+	result := []mcfgRule{}
+	mcfg = append([]mcfgRule{}, mcfg...)
+	for len(mcfg) > 0 {
+		rule := popRule(&mcfg)
+		head := rule.head
+		body := rule.body
+		if len(body) != 1 {
+			result = append(result, rule)
+			continue
+		}
+		A0x := head.args
+		k0 := len(A0x)
+		i, _, k := findTwoVars(A0x, 2)
+		if i == k0 { //no argument with two vars found
+			result = append(result, rule)
+			continue
+		}
+		si := A0x[i]
+		si1 := si[:k]
+		si2 := si[k:]
+		A0 := head.nterm
+		A1 := newNterm(dim, k0+1, A0)
+		X1 := zVars(k0 + 1)
+		X2 := varWords(X1)
+		head1 := mcfgHead{A0, concatWords(X2[0:i], [][]string{{X1[i], X1[i+1]}}, X2[i+2:k0+1])}
+		result = append(result, mcfgRule{head1, []mcfgBody{{A1, X1[0 : k0+1]}}})
+		head2 := mcfgHead{A1, concatWords(A0x[0:i], [][]string{si1, si2}, A0x[i+1:k0])}
+		mcfg = append(mcfg, mcfgRule{head2, body})
+	}
+	return result
+}
+
+// findTooLong finds an argument of length > 2, or of two terminals
+func findTooLong(args [][]string) int {
+	for i, atom := range args {
+		if len(atom) > 2 {
+			return i
+		}
+		if len(atom) == 2 && !(isVar(atom[0]) || isVar(atom[1])) {
+			return i
+		}
+	}
+	return len(args)
+}
+
+// STEP 6: peel single terminals off long arguments
+func normalStep6(mcfg []mcfgRule, dim map[string]int) []mcfgRule {
+	result := []mcfgRule{}
+	mcfg = append([]mcfgRule{}, mcfg...)
+	for len(mcfg) > 0 {
+		rule := popRule(&mcfg)
+		head := rule.head
+		body := rule.body
+		if len(body) != 1 {
+			result = append(result, rule)
+			continue
+		}
+		A0x := head.args
+		k0 := len(A0x)
+		i := findTooLong(A0x)
+		if i == k0 {
+			result = append(result, rule)
+			continue
+		}
+		si := A0x[i]
+		s1 := si[0]
+		s2 := si[1:]
+		A0 := head.nterm
+		A1 := newNterm(dim, k0+1, A0)
+		X1 := zVars(k0)
+		X2 := varWords(X1)
+		if isVar(s1) { //s2 contains only terminals
+			head1 := mcfgHead{A0, concatWords(X2[0:i], [][]string{concatSymbols([]string{X1[i]}, s2)}, X2[i+1:k0])}
+			result = append(result, mcfgRule{head1, []mcfgBody{{A1, X1[0:k0]}}})
+			head2 := mcfgHead{A1, concatWords(A0x[0:i], [][]string{{s1}}, A0x[i+1:k0])}
+			result = append(result, mcfgRule{head2, body})
+		} else {
+			head1 := mcfgHead{A0, concatWords(X2[0:i], [][]string{{s1, X1[i]}}, X2[i+1:k0])}
+			result = append(result, mcfgRule{head1, []mcfgBody{{A1, X1[0:k0]}}})
+			head2 := mcfgHead{A1, concatWords(A0x[0:i], [][]string{s2}, A0x[i+1:k0])}
+			mcfg = append(mcfg, mcfgRule{head2, body})
+		}
+	}
+	return result
+}
+
+// freshVars creates n variables Zi not in vars
+func freshVars(n int, vars []string) []string {
+	used := map[string]bool{}
+	for _, x := range vars {
+		used[x] = true
+	}
+	result := []string{}
+	for i := 0; len(result) < n; i++ {
+		X := fmt.Sprintf("Z%d", i)
+		if !used[X] {
+			result = append(result, X)
+		}
+	}
+	return result
+}
+
+// STEP 7: leave at most one argument that is not a single variable
+func normalStep7(mcfg []mcfgRule, dim map[string]int) []mcfgRule {
+	result := []mcfgRule{}
+	mcfg = append([]mcfgRule{}, mcfg...)
+	for len(mcfg) > 0 {
+		rule := popRule(&mcfg)
+		head := rule.head
+		body := rule.body
+		if len(body) != 1 {
+			result = append(result, rule)
+			continue
+		}
+		A0x := head.args
+		k0 := len(A0x)
+		i := findNonVar(A0x)
+		if i == k0 { //no non-var argument found
+			result = append(result, rule)
+			continue
+		}
+		j := findNonVar(concatWords(A0x[:i], A0x[i+1:]))
+		if j == k0-1 { //no other non-var argument found
+			result = append(result, rule)
+			continue
+		}
+		si := A0x[i]
+		xs := []string{}
+		for _, x := range si {
+			if isVar(x) {
+				xs = append(xs, x)
+			}
+		}
+		el := len(xs)
+		A0 := head.nterm
+		A1 := newNterm(dim, k0+el-1, A0)
+		X1 := freshVars(k0+el-1, xs)
+		X2 := varWords(X1)
+		head1 := mcfgHead{A0, concatWords(clampSlice(X2, 0, i), [][]string{si}, clampSlice(X2, i+el, k0+el))}
+		body1 := mcfgBody{A1, concatSymbols(clampSlice(X1, 0, i), xs, clampSlice(X1, i+el, k0+el))}
+		result = append(result, mcfgRule{head1, []mcfgBody{body1}})
+		head2 := mcfgHead{A1, concatWords(A0x[0:i], varWords(xs), A0x[i+1:k0])}
+		mcfg = append(mcfg, mcfgRule{head2, body})
+	}
+	return result
+}
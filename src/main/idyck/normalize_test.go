@@ -0,0 +1,103 @@
+package idyck
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// The golden files testdata/normal/<name>.norm.mcfg are the output of
+// mcfg.py for the passes of prepareRules followed by the normal form:
+//
+//	mcfg.py -c <name> | mcfg.py -r | mcfg.py -n > <name>.norm.mcfg
+//
+// with mcfg.py -p first for the deleting and permuting grammars. The
+// grammars alpha to bracket are the texts of the id_grammar.go constructors
+// for the parentheses 0, 1 and the brackets 0, 1, with k = 2. mcfg.py splits
+// eps into the terminals e, p and s in rules of dimension more than 1, so the
+// other grammars use eps in rules of dimension 1 only.
+var normalFormGolden = []string{
+	"alpha",
+	"beta",
+	"alpha-2parity",
+	"beta-2parity",
+	"interleaved",
+	"bracket",
+	"anbncn",
+	"copy-terminals",
+	"rank3",
+	"reserved-names",
+	"permuting",
+	"deleting",
+}
+
+// readGolden reads rules that are in normal form already, the way the
+// output of mcfg.py was read before the port
+func readGolden(t *testing.T, fileName string) MCFG {
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	ast, err := ParseMCFG(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := makeEmptyMCFG()
+	for _, rule := range ast.lower() {
+		parseRule(m, rule)
+	}
+	return *m
+}
+
+// sortedRules prints m without depending on the order of its rules
+func sortedRules(m MCFG) string {
+	lines := strings.Split(m.String(), "\n")
+	sort.Strings(lines[1:])
+	return strings.Join(lines, "\n")
+}
+
+func TestNormalFormMatchesPython(t *testing.T) {
+	for _, name := range normalFormGolden {
+		t.Run(name, func(t *testing.T) {
+			base := filepath.Join("testdata", "normal", name)
+			file, err := os.Open(base + ".mcfg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			got, err := ParseNormalForm(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := readGolden(t, base+".norm.mcfg")
+			if sortedRules(got) != sortedRules(want) {
+				t.Errorf("ParseNormalForm:\n%s\nmcfg.py:\n%s", sortedRules(got), sortedRules(want))
+			}
+		})
+	}
+}
+
+// E and Eps are ordinary names, they used to be skipped as the epsilon of
+// the built-in grammars
+func TestNoReservedNames(t *testing.T) {
+	for _, text := range []string{
+		"S(E X) :- A(E), B(X). A(a). B(b).",
+		"S(X Y) :- Eps(X), B(Y). Eps(a). B(b).",
+	} {
+		m, err := ParseNormalForm(strings.NewReader(text))
+		if err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+		paths, _, err := AllPairsReachability(context.Background(), MakeLinearGraph("ab"), &m, false, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+		if len(paths) != 1 || paths[0] != (Path{Start: 0, End: 2}) {
+			t.Errorf("%s: got %v, want [[0 2]]", text, paths)
+		}
+	}
+}
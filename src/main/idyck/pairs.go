@@ -0,0 +1,146 @@
+package idyck
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Pair files have one pair "start end" per line.
+
+// ReadPaths reads a pair file. Lines with less than two fields are skipped.
+func ReadPaths(reader io.Reader) ([]Path, error) {
+	scanner := bufio.NewScanner(reader)
+	paths := []Path{}
+	for scanner.Scan() {
+		nums := strings.Fields(scanner.Text())
+		if len(nums) < 2 {
+			continue
+		}
+		fstNum, err := strconv.Atoi(nums[0])
+		if err != nil {
+			return nil, err
+		}
+		sndNum, err := strconv.Atoi(nums[1])
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, makePath(Vertex(fstNum), Vertex(sndNum)))
+	}
+	return paths, scanner.Err()
+}
+
+// ReadPathsFromFile reads the pair file fileName, see ReadPaths.
+func ReadPathsFromFile(fileName string) ([]Path, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadPaths(file)
+}
+
+// WritePaths writes paths as a pair file, in the given order.
+func WritePaths(writer io.Writer, paths []Path) error {
+	w := bufio.NewWriter(writer)
+	for _, path := range paths {
+		fmt.Fprintf(w, "%d %d\n", path.Start, path.End)
+	}
+	return w.Flush()
+}
+
+// WritePathsToFile writes paths to the pair file fileName, see WritePaths.
+func WritePathsToFile(fileName string, paths []Path) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := WritePaths(file, paths); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Verdict classifies a pair found by some stage of the pipeline.
+type Verdict string
+
+const (
+	Reachable   Verdict = "reachable"   //in the under-approximation
+	Possible    Verdict = "possible"    //in every over-approximation, but not proven
+	Unreachable Verdict = "unreachable" //excluded by an over-approximation
+)
+
+// PairVerdict is the verdict on a pair and the stage that decided it: the
+// under-approximation for reachable pairs, the first over-approximation
+// without the pair for unreachable ones and the last over-approximation
+// for possible ones.
+type PairVerdict struct {
+	Path
+	Verdict Verdict
+	Stage   Stage
+}
+
+// Verdicts classifies every pair found by some stage of res, sorted by
+// pair. Pairs are only reachable if the underapproximation stage ran.
+func (res *Result) Verdicts() []PairVerdict {
+	found := make(map[Path]bool)
+	for _, stage := range res.Stages {
+		for _, path := range res.Paths(stage) {
+			found[path] = true
+		}
+	}
+	under := make(map[Path]bool)
+	for _, path := range res.Underapproximation {
+		under[path] = true
+	}
+	overStages := []Stage{}
+	over := make(map[Stage]map[Path]bool)
+	for _, stage := range res.Stages {
+		if stage == StageUnderapproximation {
+			continue
+		}
+		overStages = append(overStages, stage)
+		over[stage] = make(map[Path]bool)
+		for _, path := range res.Paths(stage) {
+			over[stage][path] = true
+		}
+	}
+
+	paths := []Path{}
+	for path := range found {
+		paths = append(paths, path)
+	}
+	SortPaths(paths)
+
+	verdicts := []PairVerdict{}
+	for _, path := range paths {
+		v := PairVerdict{Path: path, Verdict: Possible}
+		if under[path] {
+			v.Verdict, v.Stage = Reachable, StageUnderapproximation
+			verdicts = append(verdicts, v)
+			continue
+		}
+		for _, stage := range overStages {
+			v.Stage = stage
+			if !over[stage][path] {
+				v.Verdict = Unreachable
+				break
+			}
+		}
+		verdicts = append(verdicts, v)
+	}
+	return verdicts
+}
+
+// WriteVerdicts writes one line "start end verdict stage" per pair.
+func WriteVerdicts(writer io.Writer, verdicts []PairVerdict) error {
+	w := bufio.NewWriter(writer)
+	for _, v := range verdicts {
+		fmt.Fprintf(w, "%d %d %s %s\n", v.Start, v.End, v.Verdict, v.Stage)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,93 @@
+package idyck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPairFileRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	//in no particular order, with duplicates and large vertices
+	paths := []Path{makePath(0, 0), makePath(1<<40, 3), makePath(3, 1<<40)}
+	for i := 0; i < 100; i++ {
+		paths = append(paths, makePath(Vertex(rng.Intn(20)), Vertex(rng.Intn(20))))
+	}
+
+	var buf bytes.Buffer
+	if err := WritePaths(&buf, paths); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadPaths(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(paths) {
+		t.Errorf("got %v, want %v", got, paths)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "pairs.txt")
+	if err := WritePathsToFile(fileName, paths); err != nil {
+		t.Fatal(err)
+	}
+	got, err = ReadPathsFromFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(paths) {
+		t.Errorf("from %s got %v, want %v", fileName, got, paths)
+	}
+
+	//short lines are skipped, other fields are ignored
+	got, err = ReadPaths(strings.NewReader("1 2\n\n3\n4 5 reachable\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Path{makePath(1, 2), makePath(4, 5)}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := ReadPaths(strings.NewReader("1 x\n")); err == nil {
+		t.Errorf("no error for a vertex that is not a number")
+	}
+}
+
+func TestVerdictFile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	g := randomGraph(rng, 8, 16, _dyckTestLabels)
+	res, err := NewAnalyzer(Config{Parallelism: 1}).RunContext(context.Background(), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verdicts := res.Verdicts()
+	if len(verdicts) == 0 {
+		t.Fatal("no verdicts")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteVerdicts(&buf, verdicts); err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+
+	//the pairs can be read back as a pair file
+	paths, err := ReadPaths(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != len(verdicts) {
+		t.Fatalf("read %d pairs, wrote %d verdicts", len(paths), len(verdicts))
+	}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for i := 0; scanner.Scan(); i++ {
+		fields := strings.Fields(scanner.Text())
+		v := verdicts[i]
+		if len(fields) != 4 || paths[i] != v.Path || Verdict(fields[2]) != v.Verdict || Stage(fields[3]) != v.Stage {
+			t.Errorf("line %q for verdict %+v", scanner.Text(), v)
+		}
+	}
+}
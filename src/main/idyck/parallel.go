@@ -0,0 +1,120 @@
+package idyck
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// parallelism is the number of workers of a stage
+func (a *Analyzer) parallelism() int {
+	if a.workers > 0 {
+		return a.workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// fork returns an Analyzer for a worker: same configuration, grammar and
+// anchor, its own memo tables and provenance, and no further parallelism.
+// The heap sampler of the stage is shared.
+func (a *Analyzer) fork() *Analyzer {
+	w := &Analyzer{
+		kind:       a.kind,
+		grammar:    a.grammar,
+		parityK:    a.parityK,
+		stages:     a.stages,
+		prune:      a.prune,
+		workers:    1,
+		recordEdge: a.recordEdge,
+		bottomUp:   a.bottomUp,
+		anchor:     a.anchor,
+		mem:        a.mem,
+		grammars:   map[string]*CompiledGrammar{},
+	}
+	w.clearMaps()
+	return w
+}
+
+// forEach calls f for 0 <= i < n with up to a.parallelism() workers. With a
+// single worker f runs on a itself, otherwise every worker runs on its own
+// fork of a, so that memo tables are kept between the items of a worker.
+// The reachability statistics of the forks are appended to a in item
+// order. The first error cancels the items that are still running, skips
+// the ones that did not start and is returned; f stores its results.
+func (a *Analyzer) forEach(ctx context.Context, n int, f func(ctx context.Context, w *Analyzer, i int) error) error {
+	workers := a.parallelism()
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := f(ctx, a, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	itemStats := make([][]ReachStats, n)
+	next := make(chan int)
+	for k := 0; k < workers; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := a.fork()
+			for i := range next {
+				start := len(w.reachStats)
+				err := f(ctx, w, i)
+				itemStats[i] = w.reachStats[start:]
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+	sent := 0
+	for ; sent < n; sent++ {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- sent
+	}
+	close(next)
+	wg.Wait()
+
+	for _, stats := range itemStats {
+		a.reachStats = append(a.reachStats, stats...)
+	}
+	if firstErr == nil && sent < n {
+		//cancelled before a worker noticed
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// mapComponents runs f on every component, see forEach, and returns the
+// results in the order of comps.
+func mapComponents[T any](ctx context.Context, a *Analyzer, comps []*Graph, f func(ctx context.Context, w *Analyzer, comp *Graph) (T, error)) ([]T, error) {
+	results := make([]T, len(comps))
+	err := a.forEach(ctx, len(comps), func(ctx context.Context, w *Analyzer, i int) error {
+		res, err := f(ctx, w, comps[i])
+		results[i] = res
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,38 @@
+package idyck
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEachCancelledAfterLastItem(t *testing.T) {
+	a := NewAnalyzer(Config{Parallelism: 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const n = 8
+	var done atomic.Int32
+	err := a.forEach(ctx, n, func(ctx context.Context, w *Analyzer, i int) error {
+		if done.Add(1) == n {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("all items finished, got %v", err)
+	}
+}
+
+func TestForEachCancelledBeforeItems(t *testing.T) {
+	a := NewAnalyzer(Config{Parallelism: 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := atomic.Int32{}
+	err := a.forEach(ctx, 8, func(ctx context.Context, w *Analyzer, i int) error {
+		ran.Add(1)
+		return nil
+	})
+	if err != context.Canceled || ran.Load() != 0 {
+		t.Errorf("got %v after %d items, want %v after none", err, ran.Load(), context.Canceled)
+	}
+}
@@ -0,0 +1,200 @@
+package idyck
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/exp/slices"
+)
+
+const _epsilonParseLabel = "eps"
+
+// ParseNormalForm reads an MCFG in the .mcfg surface syntax, transforms it
+// to normal form and returns it as an MCFG.
+func ParseNormalForm(reader io.Reader) (MCFG, error) {
+	ast, err := ParseMCFG(reader)
+	if err != nil {
+		return MCFG{}, err
+	}
+	return ast.MCFG()
+}
+
+// mcfgDimensions maps every nonterminal to the number of arguments of its heads
+func mcfgDimensions(rules []mcfgRule) (map[string]int, error) {
+	dim := make(map[string]int)
+	for _, rule := range rules {
+		d, ok := dim[rule.head.nterm]
+		if ok && d != len(rule.head.args) {
+			return nil, fmt.Errorf("%s <-- head %s cannot have dimension %d and %d", rule, rule.head.nterm, d, len(rule.head.args))
+		}
+		dim[rule.head.nterm] = len(rule.head.args)
+	}
+	return dim, nil
+}
+
+// parseRule classifies a rule in normal form
+func parseRule(mcfg *MCFG, rule mcfgRule) {
+	if len(rule.body) == 0 {
+		parseBasicRule(mcfg, rule)
+		return
+	}
+	if len(rule.body) == 1 {
+		parseTwoSymbolRule(mcfg, rule)
+		return
+	}
+	parseConcatenateRule(mcfg, rule)
+}
+
+func parseTwoSymbolRule(mcfg *MCFG, rule mcfgRule) {
+	if len(rule.head.args) != len(rule.body[0].args) {
+		parseInsertRule(mcfg, rule)
+		return
+	}
+	parsePrependAppendRule(mcfg, rule)
+}
+
+// "A(X0, 1) :- A1(X0)"
+func parseInsertRule(mcfg *MCFG, rule mcfgRule) {
+	lhsName, lhsNestedTokens := rule.head.nterm, rule.head.args
+	rhsName, rhsTokens := rule.body[0].nterm, rule.body[0].args
+
+	label := ""
+	idx := -1
+
+	for i, tokens := range lhsNestedTokens {
+		token := tokens[0]
+		if slices.Contains(rhsTokens, token) {
+			continue
+		}
+		idx = i
+		label = token
+	}
+
+	if idx == -1 {
+		parseConcatenateRule(mcfg, rule)
+		return
+	}
+
+	if label == _epsilonParseLabel {
+		label = _epsilonLabel
+	}
+
+	mcfg.InsertRules = append(mcfg.InsertRules, InsertRule{
+		HeadName:      lhsName,
+		BodyName:      rhsName,
+		Label:         Label(label),
+		InsertIdx:     idx,
+		OriginalTerms: len(rhsTokens),
+	})
+}
+
+func parsePrependAppendRule(mcfg *MCFG, rule mcfgRule) {
+	lhsName, lhsNestedTokens := rule.head.nterm, rule.head.args
+	rhsName, rhsTokens := rule.body[0].nterm, rule.body[0].args
+
+	//chain rules like A(X, Y) :- B(Y, X) are concatenations of one body
+	renaming := true
+	for _, tokens := range lhsNestedTokens {
+		if len(tokens) != 1 {
+			renaming = false
+		}
+	}
+	if renaming {
+		parseConcatenateRule(mcfg, rule)
+		return
+	}
+
+	for i, tokens := range lhsNestedTokens {
+		if len(tokens) == 1 {
+			continue
+		}
+		leftmostTokenIsNonterminal := slices.Contains(rhsTokens, tokens[0])
+		isAppendRule := leftmostTokenIsNonterminal
+		if isAppendRule {
+			mcfg.AppendRules = append(mcfg.AppendRules, AppendRule{
+				HeadName:  lhsName,
+				BodyName:  rhsName,
+				Label:     Label(tokens[1]),
+				AppendIdx: i,
+				Terms:     len(rhsTokens),
+			})
+			continue
+		}
+		mcfg.PrependRules = append(mcfg.PrependRules, PrependRule{
+			HeadName:   lhsName,
+			BodyName:   rhsName,
+			Label:      Label(tokens[0]),
+			PrependIdx: i,
+			Terms:      len(rhsTokens),
+		})
+	}
+}
+
+// S(X1 Y1 X2 Y2) :- P(X1, X2), Q(Y1, Y2).
+func parseConcatenateRule(mcfg *MCFG, rule mcfgRule) {
+	headName, lhsNestedTokens := rule.head.nterm, rule.head.args
+
+	bodyNames := []string{}
+	rhsNestedTokens := [][]string{}
+	for _, atom := range rule.body {
+		rhsNestedTokens = append(rhsNestedTokens, atom.args)
+		bodyNames = append(bodyNames, atom.nterm)
+	}
+
+	termConcatenation := []TermConcatenator{}
+	for _, tokens := range lhsNestedTokens {
+		termConcatenator := TermConcatenator{}
+		for _, token := range tokens {
+			i, j := findNestedIndex(token, rhsNestedTokens)
+			termConcatenator = append(termConcatenator, TermIdentifier{
+				FromBodyIdx:     i,
+				FromIndexInBody: j,
+			})
+		}
+		termConcatenation = append(termConcatenation, termConcatenator)
+	}
+
+	mcfg.ConcatenateRules = append(mcfg.ConcatenateRules, ConcatenateRule{
+		HeadName:          headName,
+		BodyNames:         bodyNames,
+		TermConcatenation: termConcatenation,
+	})
+}
+
+func findNestedIndex(token string, nestedSlice [][]string) (int, int) {
+	for i, slice := range nestedSlice {
+		for j, el := range slice {
+			if token != el {
+				continue
+			}
+			return i, j
+		}
+	}
+	return -1, -1
+}
+
+// "A(0)"
+func parseBasicRule(mcfg *MCFG, rule mcfgRule) {
+	name, body := rule.head.nterm, rule.head.args
+
+	bodyLabel := body[0][0]
+
+	if bodyLabel == _epsilonParseLabel {
+		bodyLabel = _epsilonLabel
+	}
+
+	mcfg.BasicRules = append(mcfg.BasicRules, BasicRule{
+		HeadName: name,
+		Label:    Label(bodyLabel),
+	})
+}
+
+func makeEmptyMCFG() *MCFG {
+	return &MCFG{
+		BasicRules:       []BasicRule{},
+		PrependRules:     []PrependRule{},
+		AppendRules:      []AppendRule{},
+		InsertRules:      []InsertRule{},
+		ConcatenateRules: []ConcatenateRule{},
+	}
+}
@@ -0,0 +1,170 @@
+package idyck
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func ParseDotFile(filename string) *Graph {
+	return parseDotFile(filename, false)
+}
+
+// formats labels from i.e. "ob--XX" to "A".
+// This is used for i.e. the antlr benchmark
+func ParseDotFileAndFormatLabels(filename string) *Graph {
+	return parseDotFile(filename, true)
+}
+
+// ReadDotFile is ParseDotFile, but reports a missing or unreadable file
+// instead of returning an empty graph.
+func ReadDotFile(filename string) (*Graph, error) {
+	readFile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer readFile.Close()
+	return ParseDot(readFile)
+}
+
+// ParseDot reads the edges of a graph in the dot format of the benchmarks.
+func ParseDot(reader io.Reader) (*Graph, error) {
+	return parseDot(reader, false)
+}
+
+// WriteDot writes the edges of g in the dot format of the benchmarks, one
+// from->to[label="..."] line per edge, without the epsilon self-loops.
+func WriteDot(writer io.Writer, g *Graph) error {
+	w := bufio.NewWriter(writer)
+	for _, e := range g.edgeList {
+		if e.From == e.To && len(e.Label) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%d->%d[label=\"%s\"]\n", e.From, e.To, e.Label)
+	}
+	return w.Flush()
+}
+
+// ParseDotEdges reads the edges of a graph like ParseDot, without building
+// the Graph: duplicate edges are dropped and there are no epsilon
+// self-loops. For large graphs NewCSRGraphFromEdges makes the CSRGraph of
+// the engine from them, which is much smaller than the Graph.
+func ParseDotEdges(reader io.Reader) ([]Edge, error) {
+	fileScanner := bufio.NewScanner(reader)
+	fileScanner.Split(bufio.ScanLines)
+
+	edges := []Edge{}
+	seen := map[Edge]bool{}
+	for fileScanner.Scan() {
+		e, ok := parseDotEdge(fileScanner.Text(), false)
+		if !ok || seen[e] {
+			continue
+		}
+		seen[e] = true
+		edges = append(edges, e)
+	}
+	return edges, fileScanner.Err()
+}
+
+// ReadDotFileEdges is ParseDotEdges of a file.
+func ReadDotFileEdges(filename string) ([]Edge, error) {
+	readFile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer readFile.Close()
+	return ParseDotEdges(readFile)
+}
+
+func parseDotFile(filename string, formatLabels bool) *Graph {
+	readFile, err := os.Open(filename)
+
+	if err != nil {
+		fmt.Println(err)
+		return MakeGraph()
+	}
+	defer readFile.Close()
+
+	g, err := parseDot(readFile, formatLabels)
+	if err != nil {
+		fmt.Println(err)
+	}
+	return g
+}
+
+func parseDot(reader io.Reader, formatLabels bool) (*Graph, error) {
+	fileScanner := bufio.NewScanner(reader)
+
+	fileScanner.Split(bufio.ScanLines)
+
+	g := MakeGraph()
+	for fileScanner.Scan() {
+		line := fileScanner.Text()
+		parseDotLine(line, g, formatLabels)
+	}
+
+	return g, fileScanner.Err()
+}
+
+func parseDotLine(line string, g *Graph, formatLabels bool) {
+	e, ok := parseDotEdge(line, formatLabels)
+	if !ok {
+		return
+	}
+
+	//check if edge already exists
+	//for antlr benchmark, this cuts away about 10.000 edges (of about 70.000)
+	for _, vtx := range g.outEdges[e.From][e.Label] {
+		if vtx == e.To {
+			return
+		}
+	}
+
+	g.AddEdge(e.From, e.To, e.Label)
+}
+
+func parseDotEdge(line string, formatLabels bool) (Edge, bool) {
+
+	//need to parse lines of the form:
+	//2128493581->1164059400[label="ob--43"]
+
+	if !strings.Contains(line, "->") {
+		return Edge{}, false
+	}
+	//first split on ->
+	//then split on [label="
+	tokens1 := strings.Split(line, "->")
+
+	startVertex, _ := strconv.Atoi(tokens1[0])
+	tokens1 = strings.Split(tokens1[1], "[label=\"")
+	endVertex, _ := strconv.Atoi(tokens1[0])
+
+	var label Label
+	if formatLabels {
+		tokens1 = strings.Split(tokens1[1], "--")
+		label = Label(parseLabel(tokens1[0]))
+	} else {
+		label = Label(strings.Split(tokens1[1], "\"")[0])
+	}
+
+	return Edge{From: Vertex(startVertex), To: Vertex(endVertex), Label: label}, true
+}
+
+func parseLabel(label string) string {
+	if label == "ob" {
+		return "a"
+	}
+	if label == "op" {
+		return "b"
+	}
+	if label == "cb" {
+		return "A"
+	}
+	if label == "cp" {
+		return "B"
+	}
+	return ""
+}
@@ -0,0 +1,281 @@
+package idyck
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Parser for the .mcfg surface syntax (cf. mcfg_parse.py)
+// Reserved symbols: ( ) , . :-
+// Whitespace: ' ', \t, \r, \n, % (comment until the end of the line)
+// Names: every other sequence of characters. Names starting with a capital
+// are variables, "eps" is the empty word, other names are terminals.
+
+// ParseError is a syntax error at a position of the source.
+type ParseError struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("Parse error in Line %d, Column %d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+const mcfgSpecial = "%,.(): \n\t\r"
+
+type mcfgToken struct {
+	text string //"" at end of input
+	pos  Pos
+}
+
+func (t mcfgToken) String() string {
+	if t.text == "" {
+		return "End-of-File"
+	}
+	return t.text
+}
+
+func (t mcfgToken) isName() bool {
+	return t.text != "" && !isSpecialToken(t.text)
+}
+
+func isSpecialToken(t string) bool {
+	return t == "," || t == "." || t == "(" || t == ")" || t == ":-"
+}
+
+// mcfgParser tokenizes the input while parsing, keeping one token of lookahead
+type mcfgParser struct {
+	in        *bufio.Reader
+	line      int
+	column    int
+	lookahead *mcfgToken
+}
+
+func newMCFGParser(r io.Reader) *mcfgParser {
+	return &mcfgParser{in: bufio.NewReader(r), line: 1, column: 0}
+}
+
+func (p *mcfgParser) pos() Pos {
+	return Pos{Line: p.line, Column: p.column}
+}
+
+func (p *mcfgParser) read() (byte, bool) {
+	c, err := p.in.ReadByte()
+	if err != nil {
+		return 0, false
+	}
+	if c == '\n' {
+		p.line++
+		p.column = 0
+	} else {
+		p.column++
+	}
+	return c, true
+}
+
+func (p *mcfgParser) errorf(pos Pos, format string, a ...any) error {
+	return &ParseError{Pos: pos, Msg: fmt.Sprintf(format, a...)}
+}
+
+func (p *mcfgParser) scan() (mcfgToken, error) {
+	for {
+		c, ok := p.read()
+		if !ok {
+			return mcfgToken{pos: Pos{Line: p.line, Column: p.column + 1}}, nil
+		}
+		pos := p.pos()
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		case c == '%':
+			for ok && c != '\n' {
+				c, ok = p.read()
+			}
+		case c == ',' || c == '.' || c == '(' || c == ')':
+			return mcfgToken{string(c), pos}, nil
+		case c == ':':
+			if c, ok = p.read(); ok && c == '-' {
+				return mcfgToken{":-", pos}, nil
+			}
+			return mcfgToken{}, p.errorf(pos, `":" should only appear in ":-"`)
+		case c == '-':
+			return mcfgToken{}, p.errorf(pos, `"-" should only appear in ":-"`)
+		default:
+			word := []byte{c}
+			for {
+				next, err := p.in.Peek(1)
+				if err != nil || strings.IndexByte(mcfgSpecial, next[0]) >= 0 {
+					break
+				}
+				c, _ = p.read()
+				word = append(word, c)
+			}
+			return mcfgToken{string(word), pos}, nil
+		}
+	}
+}
+
+func (p *mcfgParser) next() (mcfgToken, error) {
+	if p.lookahead != nil {
+		t := *p.lookahead
+		p.lookahead = nil
+		return t, nil
+	}
+	return p.scan()
+}
+
+func (p *mcfgParser) peek() (mcfgToken, error) {
+	if p.lookahead != nil {
+		return *p.lookahead, nil
+	}
+	t, err := p.scan()
+	if err == nil {
+		p.lookahead = &t
+	}
+	return t, err
+}
+
+func (p *mcfgParser) expect(expected string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.text != expected {
+		return p.errorf(t.pos, `"%s" expected instead of "%s"`, expected, t)
+	}
+	return nil
+}
+
+func (p *mcfgParser) name() (Symbol, error) {
+	t, err := p.next()
+	if err != nil {
+		return Symbol{}, err
+	}
+	if !t.isName() {
+		return Symbol{}, p.errorf(t.pos, `some "name" is expected instead of "%s"`, t)
+	}
+	return Symbol{Kind: symbolKind(t.text), Name: t.text, Pos: t.pos}, nil
+}
+
+// a word of names separated by whitespace
+func (p *mcfgParser) word() (Word, error) {
+	w := Word{}
+	for {
+		s, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		w = append(w, s)
+		t, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if !t.isName() {
+			return w, nil
+		}
+	}
+}
+
+// name(arg, ..., arg)
+func (p *mcfgParser) atom(arg func() error) (Symbol, error) {
+	name, err := p.name()
+	if err != nil {
+		return name, err
+	}
+	if err := p.expect("("); err != nil {
+		return name, err
+	}
+	for {
+		if err := arg(); err != nil {
+			return name, err
+		}
+		t, err := p.peek()
+		if err != nil {
+			return name, err
+		}
+		if t.text != "," {
+			break
+		}
+		p.next()
+	}
+	return name, p.expect(")")
+}
+
+func (p *mcfgParser) head() (HeadAST, error) {
+	h := HeadAST{Args: []Word{}}
+	name, err := p.atom(func() error {
+		w, err := p.word()
+		h.Args = append(h.Args, w)
+		return err
+	})
+	h.Name, h.Pos = name.Name, name.Pos
+	return h, err
+}
+
+func (p *mcfgParser) body() (BodyAST, error) {
+	b := BodyAST{Args: []Symbol{}}
+	name, err := p.atom(func() error {
+		s, err := p.name()
+		b.Args = append(b.Args, s)
+		return err
+	})
+	b.Name, b.Pos = name.Name, name.Pos
+	return b, err
+}
+
+// Head [:- Body, ..., Body].
+func (p *mcfgParser) rule() (RuleAST, error) {
+	rule := RuleAST{Body: []BodyAST{}}
+	head, err := p.head()
+	if err != nil {
+		return rule, err
+	}
+	rule.Head, rule.Pos = head, head.Pos
+	t, err := p.peek()
+	if err != nil {
+		return rule, err
+	}
+	if t.text != "." {
+		if err := p.expect(":-"); err != nil {
+			return rule, err
+		}
+		for {
+			b, err := p.body()
+			if err != nil {
+				return rule, err
+			}
+			rule.Body = append(rule.Body, b)
+			t, err := p.peek()
+			if err != nil {
+				return rule, err
+			}
+			if t.text != "," {
+				break
+			}
+			p.next()
+		}
+	}
+	return rule, p.expect(".")
+}
+
+// ParseMCFG reads all rules of a grammar in the .mcfg surface syntax. The
+// error, if any, is a *ParseError.
+func ParseMCFG(reader io.Reader) (*GrammarAST, error) {
+	p := newMCFGParser(reader)
+	g := &GrammarAST{Rules: []RuleAST{}}
+	for {
+		t, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "" {
+			return g, nil
+		}
+		rule, err := p.rule()
+		if err != nil {
+			return nil, err
+		}
+		g.Rules = append(g.Rules, rule)
+	}
+}
@@ -0,0 +1,70 @@
+package idyck
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar string
+		pos     Pos
+		msg     string
+	}{
+		{"unclosed head", "S(X", Pos{1, 4}, `")" expected instead of "End-of-File"`},
+		{"missing dot", "S(X) :- A(X)", Pos{1, 13}, `"." expected instead of "End-of-File"`},
+		{"dash", "S(X) - A(X).", Pos{1, 6}, `"-" should only appear in ":-"`},
+		{"colon", "S(X):A(X).", Pos{1, 5}, `":" should only appear in ":-"`},
+		{"empty head", "S().", Pos{1, 3}, `some "name" is expected instead of ")"`},
+		{"empty argument", "S(X) :- A(X).\nA(a b, ).", Pos{2, 8}, `some "name" is expected instead of ")"`},
+		{"no head", ":- A(X).", Pos{1, 1}, `some "name" is expected instead of ":-"`},
+		{"later line", "S(X) :- A(X).\n% comment\nA(a)\n  B(b).", Pos{4, 3}, `":-" expected instead of "B"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseMCFG(strings.NewReader(test.grammar))
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("got %v, want a *ParseError", err)
+			}
+			if perr.Pos != test.pos || perr.Msg != test.msg {
+				t.Errorf("got %v %q, want %v %q", perr.Pos, perr.Msg, test.pos, test.msg)
+			}
+		})
+	}
+}
+
+func TestParseEpsilon(t *testing.T) {
+	text := "S(X Y) :- A(X, Y).\nA(eps, a).\nA(X, eps) :- B(X).\nB(b)."
+	ast, err := ParseMCFG(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := ast.Rules[1].Head.Args[0][0]; s.Kind != Epsilon || s.Pos != (Pos{2, 3}) {
+		t.Errorf("A(eps, a) has symbol %+v", s)
+	}
+	if s := ast.Rules[2].Head.Args[1][0]; s.Kind != Epsilon {
+		t.Errorf("A(X, eps) has symbol %+v", s)
+	}
+
+	m, err := ast.MCFG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, r := range m.BasicRules {
+		found = found || r.Label == _epsilonLabel
+	}
+	if !found {
+		t.Errorf("A(eps, a) has no basic rule for eps in %v", m)
+	}
+	found = false
+	for _, r := range m.InsertRules {
+		found = found || r.BodyName == "B" && r.Label == _epsilonLabel && r.InsertIdx == 1
+	}
+	if !found {
+		t.Errorf("A(X, eps) has no insert rule for eps in %v", m.InsertRules)
+	}
+}
@@ -0,0 +1,859 @@
+package idyck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind selects the benchmark family a graph comes from. Valueflow graphs
+// additionally require reachable pairs to have the form [s].
+type Kind int
+
+const (
+	Taint Kind = iota
+	Valueflow
+)
+
+func (k Kind) String() string {
+	if k == Valueflow {
+		return "valueflow"
+	}
+	return "taint"
+}
+
+// ParseKind returns the Kind named by s ("taint" or "valueflow").
+func ParseKind(s string) (Kind, error) {
+	switch s {
+	case "taint":
+		return Taint, nil
+	case "valueflow":
+		return Valueflow, nil
+	}
+	return Taint, fmt.Errorf("unknown benchmark kind %q", s)
+}
+
+// Stage is a step of the approximation pipeline.
+type Stage string
+
+const (
+	StageRegularization     Stage = "regularization"
+	StageIntersection       Stage = "intersection"
+	StageUnderapproximation Stage = "underapproximation"
+	StageMutualRefinement   Stage = "mutual-refinement"
+	StageStrongerGrammar    Stage = "stronger-grammar"
+	StageOnDemand           Stage = "on-demand"
+)
+
+// DefaultStages is the pipeline of the paper, in this order.
+var DefaultStages = []Stage{StageRegularization, StageIntersection, StageUnderapproximation,
+	StageMutualRefinement, StageStrongerGrammar, StageOnDemand}
+
+// DefaultPrune lists the stages whose result prunes the graph by default.
+var DefaultPrune = []Stage{StageIntersection, StageMutualRefinement, StageStrongerGrammar}
+
+// Title is the name of s in reports.
+func (s Stage) Title() string {
+	switch s {
+	case StageRegularization:
+		return "Regularization"
+	case StageIntersection:
+		return "Intersection"
+	case StageUnderapproximation:
+		return "Underapproximation"
+	case StageMutualRefinement:
+		return "Mutual refinement"
+	case StageStrongerGrammar:
+		return "Stronger Grammar"
+	case StageOnDemand:
+		return "On-Demand"
+	}
+	return string(s)
+}
+
+func (s Stage) valid() bool {
+	for _, stage := range DefaultStages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStages parses a comma separated list of stage names.
+func ParseStages(s string) ([]Stage, error) {
+	stages := []Stage{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		stage := Stage(name)
+		if !stage.valid() {
+			return nil, fmt.Errorf("unknown stage %q", name)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// Validate checks that the stages of c are known and run at most once, and
+// that only over-approximating stages that run prune the graph.
+func (c Config) Validate() error {
+	if c.Parallelism < 0 {
+		return fmt.Errorf("parallelism %d is negative", c.Parallelism)
+	}
+	stages := c.Stages
+	if stages == nil {
+		stages = DefaultStages
+	}
+	seen := make(map[Stage]bool)
+	for _, stage := range stages {
+		if !stage.valid() {
+			return fmt.Errorf("unknown stage %q", stage)
+		}
+		if seen[stage] {
+			return fmt.Errorf("stage %s runs twice", stage)
+		}
+		seen[stage] = true
+	}
+	if c.Prune == nil {
+		return nil
+	}
+	for _, stage := range c.Prune {
+		if !seen[stage] {
+			return fmt.Errorf("stage %s prunes the graph but does not run", stage)
+		}
+		if stage == StageUnderapproximation {
+			return fmt.Errorf("stage %s is an under-approximation and cannot prune the graph", stage)
+		}
+	}
+	return nil
+}
+
+// StageStats describes the run of one stage. The graph sizes are the ones
+// the stage ran on and the ones after pruning with its result (equal if the
+// stage does not prune).
+type StageStats struct {
+	Stage          Stage
+	Pairs          int
+	Pruned         bool
+	VerticesBefore int
+	EdgesBefore    int
+	VerticesAfter  int
+	EdgesAfter     int
+	Duration       time.Duration //including pruning and implicit underapproximation
+
+	Reach   []ReachStats //every all-pairs reachability computation, in order
+	Memory  MemStats
+	Partial bool //interrupted, see RunContext
+}
+
+// ReachDuration is the time spent in all-pairs reachability.
+func (s StageStats) ReachDuration() time.Duration {
+	var d time.Duration
+	for _, r := range s.Reach {
+		d += r.Duration
+	}
+	return d
+}
+
+// Derivations is the number of derivations of all reachability computations.
+func (s StageStats) Derivations() int {
+	n := 0
+	for _, r := range s.Reach {
+		n += r.Derivations
+	}
+	return n
+}
+
+// PeakWorklist is the largest worklist of all reachability computations.
+func (s StageStats) PeakWorklist() int {
+	n := 0
+	for _, r := range s.Reach {
+		if r.PeakWorklist > n {
+			n = r.PeakWorklist
+		}
+	}
+	return n
+}
+
+// Result holds the reachable pairs found by each stage of the pipeline.
+// Stages lists the stages that ran, the fields of the other stages are nil.
+type Result struct {
+	Stages []Stage
+	Stats  []StageStats //in the order of Stages
+
+	Vertices int //of the input graph
+	Edges    int
+	Duration time.Duration
+	Memory   MemStats
+
+	Interrupted Stage //running when the context was done, "" if all stages finished
+
+	Regularization     []Path
+	Intersection       []Path
+	Underapproximation []Path
+	MutualRefinement   []Path
+	StrongerGrammar    []Path
+	OnDemand           []Path
+}
+
+func (res *Result) field(stage Stage) *[]Path {
+	switch stage {
+	case StageRegularization:
+		return &res.Regularization
+	case StageIntersection:
+		return &res.Intersection
+	case StageUnderapproximation:
+		return &res.Underapproximation
+	case StageMutualRefinement:
+		return &res.MutualRefinement
+	case StageStrongerGrammar:
+		return &res.StrongerGrammar
+	case StageOnDemand:
+		return &res.OnDemand
+	}
+	return nil
+}
+
+// Paths returns the pairs found by stage, nil if it did not run.
+func (res *Result) Paths(stage Stage) []Path {
+	if f := res.field(stage); f != nil {
+		return *f
+	}
+	return nil
+}
+
+// SetPaths sets the pairs found by stage, which is added to Stages if it is
+// not there yet. It builds a Result from pair files, e.g. for Update.
+func (res *Result) SetPaths(stage Stage, paths []Path) {
+	f := res.field(stage)
+	if f == nil {
+		return
+	}
+	*f = paths
+	for _, s := range res.Stages {
+		if s == stage {
+			return
+		}
+	}
+	res.Stages = append(res.Stages, stage)
+}
+
+func (res *Result) sort() {
+	for _, stage := range res.Stages {
+		SortPaths(res.Paths(stage))
+	}
+}
+
+// Run executes the whole approximation pipeline on g:
+// Regularization -> Intersection -> Underapproximation -> Mutual refinement
+// -> Stronger Grammar -> On-Demand. The graph is pruned between stages.
+func Run(g *Graph, kind Kind) Result {
+	return NewAnalyzer(Config{Kind: kind}).Run(g)
+}
+
+// Run is RunContext without a deadline.
+func (a *Analyzer) Run(g *Graph) Result {
+	res, _ := a.RunContext(context.Background(), g)
+	return res
+}
+
+// RunContext executes the stages of the configuration of a in order. After
+// a pruning stage only the edges on a path between its pairs are kept.
+// Mutual refinement and on-demand refinement need the under-approximation,
+// which is computed on the current graph if its stage did not run before.
+// On-demand refinement refines the result of the last over-approximating
+// stage, or all pairs connected in the graph.
+//
+// If ctx is done, the results of the finished stages are returned with
+// ctx.Err() and Result.Interrupted names the stage that was running. An
+// interrupted on-demand refinement is kept as a partial result: the pairs
+// it did not refine yet are included, so it still over-approximates.
+func (a *Analyzer) RunContext(ctx context.Context, g *Graph) (Result, error) {
+
+	startTime := time.Now()
+	runMem := newMemSampler()
+	res := Result{
+		Stages:   []Stage{},
+		Stats:    []StageStats{},
+		Vertices: g.NumVertices(),
+		Edges:    g.NumEdges(),
+	}
+
+	//removing vertices and edges from valueflow that are not reachable 
+	//through a path that [s]
+	if a.valueflow() {
+		g = g.removeValueflowUnreachable()
+	}
+
+	var underApprox, overApprox []Path
+	under := func() ([]Path, error) {
+		if underApprox == nil {
+			paths, err := a.UnderApprox(ctx, g)
+			if err != nil {
+				return nil, err
+			}
+			underApprox = paths
+		}
+		return underApprox, nil
+	}
+
+	var err error
+	for _, stage := range a.stages {
+		if err = ctx.Err(); err != nil {
+			res.Interrupted = stage
+			break
+		}
+		stageStart := time.Now()
+		a.reachStats = []ReachStats{}
+		a.mem = newMemSampler()
+		stats := StageStats{
+			Stage:          stage,
+			VerticesBefore: g.NumVertices(),
+			EdgesBefore:    g.NumEdges(),
+		}
+		var paths, underPaths []Path
+		switch stage {
+		case StageRegularization:
+			//for valueflow bracket condition is included in automaton
+			paths, err = a.AutomatonReachability(ctx, g)
+		case StageIntersection:
+			paths, err = a.IntersectionReachability(ctx, g)
+		case StageUnderapproximation:
+			//through D(\Sigma_{\alpha}\cup\Sigma_{\beta})
+			underApprox = nil
+			paths, err = under()
+		case StageMutualRefinement:
+			if underPaths, err = under(); err == nil {
+				a.SetGrammar(Classic)
+				paths, err = a.MROverApprox(ctx, g, underPaths)
+			}
+		case StageStrongerGrammar:
+			if underPaths, err = under(); err == nil {
+				a.SetGrammar(Augmented)
+				paths, err = a.MROverApprox(ctx, g, underPaths)
+			}
+		case StageOnDemand:
+			if overApprox == nil {
+				overApprox = a.anchored(g.getAllPaths())
+			}
+			if underPaths, err = under(); err == nil {
+				paths, err = a.onDemand(ctx, g, underPaths, overApprox)
+			}
+		default:
+			a.mem, a.reachStats = nil, nil
+			continue
+		}
+		if stage == StageUnderapproximation {
+			paths = a.anchored(paths)
+		}
+
+		if err != nil {
+			res.Interrupted = stage
+			//only on-demand refinement has a useful partial result
+			if stage != StageOnDemand || paths == nil {
+				a.mem, a.reachStats = nil, nil
+				break
+			}
+			stats.Partial = true
+		}
+
+		res.Stages = append(res.Stages, stage)
+		*res.field(stage) = paths
+		if stage != StageUnderapproximation {
+			overApprox = paths
+		}
+
+		//remove useless edges (not in path among reachable pair)
+		//from now on these edges cannot influence the answer
+		if a.prune[stage] && err == nil {
+			g = g.RemoveNotPath(paths)
+			_, _, g = ParseDyckComponent(g)
+			stats.Pruned = true
+		}
+
+		stats.Pairs = len(paths)
+		stats.VerticesAfter = g.NumVertices()
+		stats.EdgesAfter = g.NumEdges()
+		stats.Duration = time.Since(stageStart)
+		a.mem.sample()
+		stats.Memory = a.mem.stats()
+		stats.Reach = a.reachStats
+		if stats.Memory.PeakHeap > runMem.peak {
+			runMem.peak = stats.Memory.PeakHeap
+		}
+		a.mem, a.reachStats = nil, nil
+		res.Stats = append(res.Stats, stats)
+		if err != nil {
+			break
+		}
+	}
+
+	res.sort()
+	res.Duration = time.Since(startTime)
+	runMem.sample()
+	res.Memory = runMem.stats()
+	return res, err
+}
+
+// onDemand refines overApprox with the classic grammars and the result with
+// the augmented grammars.
+func (a *Analyzer) onDemand(ctx context.Context, g *Graph, underApprox []Path, overApprox []Path) ([]Path, error) {
+	a.SetGrammar(Classic)
+	filteredClassicPaths, err := a.OnDemandMR(ctx, g, underApprox, overApprox)
+	if err != nil {
+		return filteredClassicPaths, err
+	}
+	g = g.RemoveNotPath(filteredClassicPaths)
+	_, _, g = ParseDyckComponent(g)
+
+	a.SetGrammar(Augmented)
+	return a.OnDemandMR(ctx, g, underApprox, filteredClassicPaths)
+}
+
+// AutomatonReachability over-approximates reachability by intersecting the
+// parenthesis Dyck language with a regular approximation of the brackets.
+func (a *Analyzer) AutomatonReachability(ctx context.Context, g *Graph) ([]Path, error) {
+	a.recordEdge = false
+	defer func() { a.recordEdge = true }()
+
+	compPaths, err := mapComponents(ctx, a, g.splitComponents(), func(ctx context.Context, a *Analyzer, gComp *Graph) ([]Path, error) {
+		if len(gComp.edgeList) == len(gComp.vertices) {
+			return nil, nil
+		}
+		parList, braList, comp := parseDyckComponentNaive(gComp)
+		comp = comp.multiplyByAutomaton(braList, a.valueflow())
+		defer a.withAnchor(a.anchor.mapped(func(v Vertex) []Vertex {
+			return automatonVertices(v, braList, a.valueflow(), a.anchor.sink)
+		}))()
+		alphaGrammar, _ := DyckAlphaGrammar(parList, braList)
+		paths, err := a.allPairsReachability(ctx, comp, compileGrammar(&alphaGrammar), nil)
+		if err != nil {
+			return nil, err
+		}
+		return filterAutomatonPaths(paths, braList, a.valueflow()), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	alphaPaths := []Path{}
+	for _, paths := range compPaths {
+		alphaPaths = append(alphaPaths,paths...)
+	}
+	return alphaPaths, nil
+}
+
+// IntersectionReachability returns the pairs reachable under both the alpha
+// and the beta grammar.
+func (a *Analyzer) IntersectionReachability(ctx context.Context, g *Graph) ([]Path, error) {
+
+	a.recordEdge = false
+	defer func() { a.recordEdge = true }()
+
+	type compResult struct {
+		alpha, beta, bracket []Path
+	}
+	compResults, err := mapComponents(ctx, a, g.splitComponents(), func(ctx context.Context, a *Analyzer, gComp *Graph) (compResult, error) {
+		var res compResult
+		//empty graph (ignoring trivial paths)
+		if len(gComp.edgeList) == len(gComp.vertices) {
+			return res, nil
+		}
+		//find paths that respect alphaGrammar
+		parList, braList, comp := parseDyckComponentNaive(gComp)
+		alphaGrammar, _ := DyckAlphaGrammar(parList, braList)
+		alphaPathsComp, err := a.allPairsReachability(ctx, comp, compileGrammar(&alphaGrammar), nil)
+		if err != nil {
+			return res, err
+		}
+		res.alpha = alphaPathsComp
+		
+		//reduce the graph with information from alpha paths
+		comp = comp.RemoveNotPath(alphaPathsComp)
+		parList, braList, comp = parseDyckComponentNaive(comp)
+
+		//find paths that respect betaGrammar
+		betaGrammar, _ := DyckBetaGrammar(parList, braList)
+		betaPathsComp, err := a.allPairsReachability(ctx, comp, compileGrammar(&betaGrammar), nil)
+		if err != nil {
+			return res, err
+		}
+		res.beta = betaPathsComp
+
+		if a.valueflow() {
+			res.bracket = g.filterBracketPaths(betaPathsComp)
+		}
+		return res, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	alphaPaths := []Path{}
+	betaPaths := make(map[Path]bool)
+	bracketPaths := make(map[Path]bool)
+	for _, res := range compResults {
+		alphaPaths = append(alphaPaths,res.alpha...)
+		for _, path := range res.beta {
+			betaPaths[path]=true
+		}
+		for _, path := range res.bracket {
+			bracketPaths[path]=true
+		}
+	}
+
+	overPaths := []Path{}
+	for _, alphaPath := range alphaPaths {
+		if betaPaths[alphaPath] && alphaPath.Start != alphaPath.End {
+			if !a.valueflow() || bracketPaths[alphaPath] {
+				overPaths = append(overPaths, alphaPath)
+			}
+		}
+	}
+
+	return overPaths, nil
+}
+
+// UnderApprox returns pairs reachable through D(Sigma_alpha cup Sigma_beta),
+// an under-approximation of interleaved Dyck reachability.
+func (a *Analyzer) UnderApprox(ctx context.Context, g *Graph) ([]Path, error) {
+
+	//all pairs, also when anchored: they condense the graph for refinement
+	defer a.withAnchor(nil)()
+
+	_, _, gCopy := parseDyckComponentNaive(g)
+
+	if a.valueflow() {
+		gCopy = gCopy.valueflowTransformation()
+	}
+
+	a.recordEdge = false
+	defer func() { a.recordEdge = true }()
+
+	compPaths, err := mapComponents(ctx, a, gCopy.splitComponents(), func(ctx context.Context, a *Analyzer, gComp *Graph) ([]Path, error) {
+
+		//empty graph (ignoring trivial paths)
+		if len(gComp.edgeList) == len(gComp.vertices) {
+			return nil, nil
+		}
+
+		//find paths that respect InterleavedDyckGrammar
+		parList, braList, comp := ParseDyckComponent(gComp)
+
+		grammar, _ := InterleavedDyckGrammar(parList, braList)
+		return a.allPairsReachability(ctx, comp, compileGrammar(&grammar), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reachablePaths := []Path{}
+	for _, paths := range compPaths {
+		reachablePaths = append(reachablePaths,paths...)
+	}
+
+	filteredReachable := []Path{}
+	for _, path := range reachablePaths {
+		if path.Start != path.End {
+			filteredReachable = append(filteredReachable,path)
+		}
+	}
+
+	if a.valueflow() {
+		return filterValueflowPaths(filteredReachable), nil
+	}
+	return filteredReachable, nil
+}
+
+// MROverApprox runs mutual refinement on g after merging the vertices that
+// underApprox proves mutually reachable.
+func (a *Analyzer) MROverApprox(ctx context.Context, g *Graph, underApprox []Path) ([]Path, error) {
+
+	//merge mutually reachable vertices
+	condensedGraph, parent := condensateFromUnderApprox(g, underApprox)
+
+	restore := a.withAnchor(a.anchor.mapped(func(v Vertex) []Vertex {
+		return []Vertex{findPMR(v, &parent)}
+	}))
+    MRCondensedOverPaths, err := a.MutualRefinement(ctx, condensedGraph, false, makePath(Vertex(0), Vertex(0)))
+    restore()
+    if err != nil {
+    	return nil, err
+    }
+
+    afterTrans := make(map[Vertex][]Vertex)
+    for chi, par := range parent {
+    	afterTrans[par] = append(afterTrans[par],chi)
+    }
+
+    MROverPaths := []Path{}
+    for _, path := range MRCondensedOverPaths {
+    	if path.Start == path.End {
+    		continue
+    	}
+    	for _, ini := range afterTrans[path.Start] {
+    		for _, fin := range afterTrans[path.End] {
+    			if ini == fin {
+    				continue
+    			}
+				MROverPaths = append(MROverPaths, makePath(ini,fin))
+    		}
+    	}
+    }
+
+    for v, _ := range condensedGraph.vertices {
+    	for _, ini := range afterTrans[v] {
+    		for _, fin := range afterTrans[v] {
+    			if ini == fin {
+    				continue
+    			}
+				MROverPaths = append(MROverPaths, makePath(ini,fin))
+    		}
+    	}
+    }
+
+	return a.anchored(MROverPaths), nil
+
+}
+
+//make union find for guys that are reachable
+//only run mutual refinement path if both of them are their own parents
+//store the answer
+//afterwards run again
+
+// OnDemandMR refines every pair of overApprox not in underApprox by running
+// mutual refinement on that single pair, unless Update keeps it. Only
+// pairs of representatives of the condensed graph are refined, in
+// parallel; the other pairs take the answer of their representatives. If
+// ctx is done, the pairs that are not refined yet are kept and returned
+// with ctx.Err().
+func (a *Analyzer) OnDemandMR(ctx context.Context, g *Graph, underApprox []Path, overApprox []Path) ([]Path, error) {
+
+	condensedGraph, parent := condensateFromUnderApprox(g, underApprox)
+
+	underMap := make(map[Path]bool)
+	for _, pair := range underApprox {
+		underMap[pair] = true
+	}
+
+	unknownPaths := []Path{}
+	for _, path := range overApprox {
+		if !underMap[path] {
+			unknownPaths = append(unknownPaths, path)
+		}
+	}
+
+	//process paths that exist in condensed graph first
+	//then the others can have their answer derived
+	uRoot := make(map[Vertex][]Path)
+	uDerived := []Path{}
+	for _, uPath := range unknownPaths {
+		if uPath.Start == findPMR(uPath.Start, &parent) && uPath.End == findPMR(uPath.End, &parent) {
+			uRoot[uPath.Start] = append(uRoot[uPath.Start], uPath)
+		} else {
+			uDerived = append(uDerived, uPath)
+		}
+	}
+	roots := []Vertex{}
+	for root := range uRoot {
+		roots = append(roots, root)
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+	rootPaths := []Path{}
+	for _, root := range roots {
+		rootPaths = append(rootPaths, uRoot[root]...)
+	}
+
+	//refine the root pairs in parallel, each worker with its own caches
+	refined := make([]bool, len(rootPaths))
+	reachable := make([]bool, len(rootPaths))
+	for i, root := range rootPaths {
+		if a.kept[root] {
+			refined[i], reachable[i] = true, true
+		}
+	}
+	restore := a.withAnchor(a.anchor.mapped(func(v Vertex) []Vertex {
+		return []Vertex{findPMR(v, &parent)}
+	}))
+	err := a.forEach(ctx, len(rootPaths), func(ctx context.Context, w *Analyzer, i int) error {
+		if refined[i] {
+			return nil
+		}
+		if w.queries%100 == 0 {
+			w.clearMaps()
+		}
+		w.queries++
+		paths, err := w.MutualRefinement(ctx, condensedGraph, true, rootPaths[i])
+		if err != nil {
+			return err
+		}
+		refined[i] = true
+		reachable[i] = len(paths) > 0
+		return nil
+	})
+	restore()
+
+	//if ctx is done everything not refined yet is still possible
+	memory := make(map[Path]bool)
+	refinedRoots := make(map[Path]bool)
+	filteredOverPaths := append([]Path{}, a.anchored(underApprox)...)
+	for i, currPath := range rootPaths {
+		if refined[i] {
+			refinedRoots[currPath] = true
+		}
+		if reachable[i] {
+			memory[currPath] = true
+		}
+		if reachable[i] || !refined[i] {
+			filteredOverPaths = append(filteredOverPaths, currPath)
+		}
+	}
+
+	//the answer of the other pairs is the one of their roots
+	for _, currPath := range uDerived {
+		root := makePath(findPMR(currPath.Start, &parent), findPMR(currPath.End, &parent))
+		if memory[root] || err != nil && !refinedRoots[root] {
+			filteredOverPaths = append(filteredOverPaths, currPath)
+		}
+	}
+
+	return filteredOverPaths, err
+}
+
+
+// MutualRefinement alternates the alpha and beta grammars, pruning g to the
+// edges used by each, until the graph stops shrinking. With onePath set only
+// myPath is tracked. Nothing is returned if ctx is done before it converges.
+// The components of g are refined in parallel, see forEach.
+func (a *Analyzer) MutualRefinement(ctx context.Context, g *Graph, onePath bool, myPath Path) ([]Path, error) {
+
+	if onePath && (!g.vertices[myPath.Start] || !g.vertices[myPath.End]) {
+		return []Path{}, nil
+	}
+
+	components := g.splitComponents()
+
+	//only the component containing mypath matters
+	if onePath {
+		for _, comp := range components {
+			if comp.vertices[myPath.Start] && comp.vertices[myPath.End] {
+				return a.refineComponent(ctx, comp.RemoveNotPath([]Path{myPath}), true, myPath)
+			}
+		}
+		return []Path{}, nil
+	}
+
+	compPaths, err := mapComponents(ctx, a, components, func(ctx context.Context, a *Analyzer, comp *Graph) ([]Path, error) {
+		return a.refineComponent(ctx, comp, false, myPath)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []Path{}
+	for _, p := range compPaths {
+		paths = append(paths, p...)
+	}
+	return paths, nil
+}
+
+// refineComponent runs one round of mutual refinement on a connected
+// component and recurses on what is left of it.
+func (a *Analyzer) refineComponent(ctx context.Context, comp *Graph, onePath bool, myPath Path) ([]Path, error) {
+
+	paths := []Path{}
+	var goal *Path
+	if onePath && !a.bottomUp {
+		goal = &myPath
+	}
+	parList, braList, parsedComp := ParseDyckComponent(comp)
+	oldEdgeNum := len(parsedComp.GetEdges())
+	alphaPaths, err := a.getAlphaPaths(ctx, parsedComp, parList, braList, goal)
+	if err != nil {
+		return nil, err
+	}
+	if onePath {
+		found := false
+		for _, path := range alphaPaths {
+			if path == myPath {
+				found = true
+			}
+		}
+		if !found {
+			return []Path{}, nil
+		}
+		alphaPaths = []Path{myPath}
+	}
+	alphaEdges := a.usedEdges(&alphaPaths)
+	parsedComp = getGraphFromEdgeMap(alphaEdges)
+	parList, braList, parsedComp = ParseDyckComponent(parsedComp)
+
+	betaPaths, err := a.getBetaPaths(ctx, parsedComp, parList, braList, goal)
+	if err != nil {
+		return nil, err
+	}
+	if onePath {
+		found := false
+		for _, path := range betaPaths {
+			if path == myPath {
+				found = true
+			}
+		}
+		if !found {
+			return []Path{}, nil
+		}
+		betaPaths = []Path{myPath}
+	}
+	betaEdges := a.usedEdges(&betaPaths)
+	parsedComp = getGraphFromEdgeMap(betaEdges)
+
+	if a.valueflow() {
+		betaPaths = parsedComp.filterBracketPaths(betaPaths)
+		if onePath && len(betaPaths) == 0 {
+			return []Path{}, nil
+		}
+		parsedComp = parsedComp.removeValueflowUnreachable()
+	}
+
+	parList, braList, parsedComp = ParseDyckComponent(parsedComp)
+	currEdgeNum := len(parsedComp.GetEdges())
+
+	if currEdgeNum == 0 || oldEdgeNum == currEdgeNum {
+		//it has converged
+		if onePath && len(alphaPaths)>0 && len(betaPaths)>0 {
+			return alphaPaths, nil
+		}
+		betaPathMap := make(map[Path]bool)
+		for _, betaPath := range betaPaths {
+			betaPathMap[betaPath] = true
+		}
+		for _, alphaPath := range alphaPaths {
+			if alphaPath.Start != alphaPath.End && betaPathMap[alphaPath] {
+				paths = append(paths, alphaPath)
+			}
+		}
+	} else {
+		newPaths, err := a.MutualRefinement(ctx, parsedComp, onePath, myPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range newPaths {
+			if onePath && path != myPath {
+				continue
+			}
+			paths = append(paths, path)
+		}
+		if onePath && len(newPaths)==0 {
+			return []Path{}, nil
+		}
+	}
+
+	return paths, nil
+}
+
+
@@ -0,0 +1,128 @@
+package idyck
+
+import (
+	"context"
+	"fmt"
+)
+
+// Query decides whether pair.End is reachable from pair.Start without
+// running the stages on all pairs. The stages of the configuration of a run
+// in order on the edges that lie on some path from pair.Start to
+// pair.End: the under-approximation proves the pair reachable, an
+// over-approximation without the pair proves it unreachable. Mutual
+// refinement, the stronger grammar and on-demand refinement refine only
+// this pair. The verdict is Possible if no stage decides, with the last
+// over-approximation that ran.
+//
+// An error is returned if pair.Start and pair.End are the same vertex or
+// not both vertices of g. If ctx is done the verdict so far is returned
+// with ctx.Err().
+func (a *Analyzer) Query(ctx context.Context, g *Graph, pair Path) (PairVerdict, error) {
+	verdict := PairVerdict{Path: pair, Verdict: Possible}
+	if pair.Start == pair.End {
+		return verdict, fmt.Errorf("query %v: start and end are the same vertex", pair)
+	}
+	for _, v := range []Vertex{pair.Start, pair.End} {
+		if !g.vertices[v] {
+			return verdict, fmt.Errorf("query %v: %d is not a vertex of the graph", pair, v)
+		}
+	}
+
+	if a.valueflow() {
+		g = g.removeValueflowUnreachable()
+	}
+	restrict := func(g *Graph) *Graph {
+		_, _, g = ParseDyckComponent(g.RemoveNotPath([]Path{pair}))
+		return g
+	}
+	g = restrict(g)
+
+	var underApprox []Path
+	under := func() ([]Path, error) {
+		if underApprox == nil {
+			paths, err := a.UnderApprox(ctx, g)
+			if err != nil {
+				return nil, err
+			}
+			underApprox = paths
+		}
+		return underApprox, nil
+	}
+	//refine runs mutual refinement on pair with the grammars of profile,
+	//in the graph condensed by the under-approximation as OnDemandMR
+	refine := func(profiles ...GrammarProfile) (bool, error) {
+		underPaths, err := under()
+		if err != nil {
+			return false, err
+		}
+		condensedGraph, parent := condensateFromUnderApprox(g, underPaths)
+		root := makePath(findPMR(pair.Start, &parent), findPMR(pair.End, &parent))
+		for _, profile := range profiles {
+			a.SetGrammar(profile)
+			paths, err := a.MutualRefinement(ctx, condensedGraph, true, root)
+			if err != nil || len(paths) == 0 {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	for _, stage := range a.stages {
+		if err := ctx.Err(); err != nil {
+			return verdict, err
+		}
+		var paths []Path
+		var found bool
+		var err error
+		switch stage {
+		case StageRegularization:
+			paths, err = a.AutomatonReachability(ctx, g)
+			found = containsPath(paths, pair)
+		case StageIntersection:
+			paths, err = a.IntersectionReachability(ctx, g)
+			found = containsPath(paths, pair)
+		case StageUnderapproximation:
+			underApprox = nil
+			paths, err = under()
+			if err == nil && containsPath(paths, pair) {
+				verdict.Verdict, verdict.Stage = Reachable, stage
+				return verdict, nil
+			}
+			if err != nil {
+				return verdict, err
+			}
+			continue
+		case StageMutualRefinement:
+			found, err = refine(Classic)
+		case StageStrongerGrammar:
+			found, err = refine(Augmented)
+		case StageOnDemand:
+			found, err = refine(Classic, Augmented)
+		default:
+			continue
+		}
+		if err != nil {
+			return verdict, err
+		}
+
+		verdict.Stage = stage
+		if !found {
+			verdict.Verdict = Unreachable
+			return verdict, nil
+		}
+		if paths != nil && a.prune[stage] {
+			g = restrict(g.RemoveNotPath(paths))
+			underApprox = nil
+		}
+	}
+	return verdict, nil
+}
+
+func containsPath(paths []Path, path Path) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
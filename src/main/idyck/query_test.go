@@ -0,0 +1,71 @@
+package idyck
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+// TestQuery checks the verdict of Query on every pair against a run on all
+// pairs. A reachable pair is in the under-approximation and in the result
+// of on-demand refinement, a possible pair only in the latter. An
+// unreachable pair is excluded by some over-approximation of the run, not
+// necessarily by on-demand refinement: the stages prune edges, not pairs,
+// so a later stage can keep a pair that an earlier one excluded.
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	count := map[Verdict]int{}
+	for _, kind := range []Kind{Taint, Valueflow} {
+		for i := 0; i < 20; i++ {
+			g := randomGraph(rng, 6, 12, _dyckTestLabels)
+			res, err := NewAnalyzer(Config{Parallelism: 1, Kind: kind}).RunContext(ctx, g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := NewAnalyzer(Config{Parallelism: 1, Kind: kind})
+			for _, u := range g.Vertices() {
+				for _, v := range g.Vertices() {
+					if u == v {
+						continue
+					}
+					pair := makePath(u, v)
+					verdict, err := a.Query(ctx, g, pair)
+					if err != nil {
+						t.Fatal(err)
+					}
+					under, onDemand := containsPath(res.Underapproximation, pair), containsPath(res.OnDemand, pair)
+					excluded := false
+					for _, stage := range res.Stages {
+						excluded = excluded || stage != StageUnderapproximation && !containsPath(res.Paths(stage), pair)
+					}
+					if verdict.Verdict == Reachable && !(under && onDemand) ||
+						verdict.Verdict == Possible && !(!under && onDemand) ||
+						verdict.Verdict == Unreachable && !(!under && excluded) {
+						t.Errorf("%s graph %d: %v is %s by %s, in the under-approximation %v, on-demand %v", kind, i, pair, verdict.Verdict, verdict.Stage, under, onDemand)
+					}
+					count[verdict.Verdict]++
+				}
+			}
+		}
+	}
+	if count[Reachable] == 0 || count[Possible] == 0 || count[Unreachable] == 0 {
+		t.Errorf("verdicts %v", count)
+	}
+}
+
+func TestQueryNotAVertex(t *testing.T) {
+	g := MakeGraph()
+	g.AddEdge(1, 2, "op--0")
+	g.AddEdge(2, 3, "cp--0")
+	a := NewAnalyzer(Config{Parallelism: 1})
+	for _, pair := range []Path{makePath(1, 99), makePath(99, 3), makePath(98, 99)} {
+		if _, err := a.Query(context.Background(), g, pair); err == nil {
+			t.Errorf("no error for %v", pair)
+		}
+	}
+	verdict, err := a.Query(context.Background(), g, makePath(1, 3))
+	if err != nil || verdict.Verdict != Reachable {
+		t.Errorf("got %v, %v for [1 3]", verdict, err)
+	}
+}
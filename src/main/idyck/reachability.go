@@ -0,0 +1,698 @@
+package idyck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"golang.org/x/exp/slices"
+	//"strconv"
+)
+
+// HYPERPARAMETERS
+const _startNonTerminal = "S"
+
+const (
+	logging = false
+)
+
+//the context is checked every _ctxCheckInterval worklist items
+const _ctxCheckInterval = 256
+
+//END HYPERPARAMETERS
+
+type reach struct {
+	graph                *CSRGraph
+	grammar              *CompiledGrammar
+	worklistIdx          int
+	worklist             []derivation
+	derivations          [][]*derivation //by nonterminal
+	seen                 map[derivationKey]bool
+	tails                map[derivationKey]int32 //interned segments after the first _keySegments
+	derivationVertexMap  map[derivationVertex][]*derivation
+	vertexSCC            []int32 //by dense id of graph
+	reachabilitySCC      *sccReachability
+	recordEdge           bool
+	prov                 *provenance
+	witness              witnesses //nil unless witness paths or derivation DAGs are wanted
+	demand               *demand //nil unless goal-directed, see newGoalReach
+	stats                ReachStats
+	sample               func() //called every _memSampleInterval worklist items, may be nil
+}
+
+// Path is a pair of vertices; a reachable Path means End is reachable from Start.
+type Path struct {
+	Start    Vertex
+	End      Vertex
+}
+
+type derivation struct {
+	segments   segments
+	key        derivationKey //its nonterminal is key.name
+}
+
+//the first _keySegments segments of a derivation are stored in its key,
+//the others are interned
+const _keySegments = 2
+
+// derivationKey identifies a derivation exactly. The dimension of a
+// nonterminal is fixed, so unused segments can be left zero.
+type derivationKey struct {
+	name int32 //nonterminal of the CompiledGrammar, 0 is the start nonterminal
+	segs [_keySegments]Path
+	tail int32 //interned remaining segments, 0 if there are none
+}
+
+// derivationID numbers the derivations of one reachability computation
+type derivationID int32
+
+// provenance records for every derivation the edges it reads and the
+// derivations it is derived from, see usedEdges
+type provenance struct {
+	ids        map[derivationKey]derivationID
+	deriToEdge map[derivationID][]Edge
+	deriToDeri map[[2]derivationID]bool
+}
+
+func newProvenance() *provenance {
+	return &provenance{
+		ids:        map[derivationKey]derivationID{},
+		deriToEdge: map[derivationID][]Edge{},
+		deriToDeri: map[[2]derivationID]bool{},
+	}
+}
+
+// id returns the id of the derivation with key, numbering it if it is new
+func (p *provenance) id(key derivationKey) derivationID {
+	id, ok := p.ids[key]
+	if !ok {
+		id = derivationID(len(p.ids))
+		p.ids[key] = id
+	}
+	return id
+}
+
+// startID returns the id of S(path), false if it was not derived
+func (p *provenance) startID(path Path) (derivationID, bool) {
+	id, ok := p.ids[derivationKey{segs: [_keySegments]Path{path}}]
+	return id, ok
+}
+
+func (p *provenance) recordEdge(d *derivation, edge Edge) {
+	id := p.id(d.key)
+	p.deriToEdge[id] = append(p.deriToEdge[id], edge)
+}
+
+func (p *provenance) recordDerivation(d *derivation, from *derivation) {
+	p.deriToDeri[[2]derivationID{p.id(d.key), p.id(from.key)}] = true
+}
+
+// newDerivation returns the derivation name(segs) with its key
+func (r *reach) newDerivation(name int32, segs segments) derivation {
+	return derivation{
+		segments: segs,
+		key:      r.segmentsKey(name, segs),
+	}
+}
+
+func (r *reach) segmentsKey(name int32, segs segments) derivationKey {
+	key := derivationKey{name: name}
+	copy(key.segs[:], segs)
+	if len(segs) > _keySegments {
+		//the length is part of the key of a tail, it is shared by all nonterminals
+		rest := r.segmentsKey(-int32(len(segs)), segs[_keySegments:])
+		tail, ok := r.tails[rest]
+		if !ok {
+			tail = int32(len(r.tails)+1)
+			r.tails[rest] = tail
+		}
+		key.tail = tail
+	}
+	return key
+}
+
+type derivationVertex struct {
+	name int32
+	dimension int
+	start bool
+	vertex Vertex
+}
+
+
+type segments []Path
+
+type nameToDerivations map[string][]*derivation
+
+func (p Path) Equals(p2 Path) bool {
+	return p.Start == p2.Start && p.End == p2.End
+}
+
+func (s segments) Equals(s2 segments) bool {
+	if len(s) != len(s2) {
+		return false
+	}
+	for i, el := range s {
+		if !el.Equals(s2[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d derivation) Equals(d2 derivation) bool {
+	return d.key == d2.key
+}
+
+func logg(a ...any) {
+	if logging {
+		fmt.Println(a...)
+	}
+}
+
+func hasEdge(g *Graph, from Vertex, to Vertex, label Label) bool {
+	for _, edge := range g.GetEdges() {
+		if edge.From == from && edge.To == to && edge.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+// AllPairsReachability returns the pairs of vertices of g connected by a
+// path whose label is derived from the start nonterminal of m. The grammar
+// is validated and compiled first. If ctx is done before the computation
+// finishes, the pairs found so far are returned with ctx.Err().
+func AllPairsReachability(ctx context.Context, g *Graph, m *MCFG, interleaved bool, refinedPairs [][]Vertex, charList ...[]int) ([]Path, nameToDerivations, error) {
+	grammar, err := CompileGrammar(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	reachData := newReach(g, grammar, false)
+	paths, err := reachData.run(ctx)
+	return paths, reachData.nameToDerivations(), err
+}
+
+// ReachCompiled is AllPairsReachability for a grammar compiled before, to
+// run it on many graphs.
+func ReachCompiled(ctx context.Context, g *Graph, grammar *CompiledGrammar) ([]Path, error) {
+	return newReach(g, grammar, false).run(ctx)
+}
+
+// ReachCSR is ReachCompiled on a CSRGraph. Made with NewCSRGraphFromEdges
+// it spares building the Graph of large inputs.
+func ReachCSR(ctx context.Context, c *CSRGraph, grammar *CompiledGrammar) ([]Path, error) {
+	return newReachCSR(c, grammar, false).run(ctx)
+}
+
+func newReach(g *Graph, grammar *CompiledGrammar, recordEdge bool) *reach {
+	return newReachCSR(NewCSRGraph(g), grammar, recordEdge)
+}
+
+func newReachCSR(c *CSRGraph, grammar *CompiledGrammar, recordEdge bool) *reach {
+	logg("--- begin all pairs reachability ---")
+
+	//startTime := time.Now()
+	vSCC, rSCC := c.findSccs()
+
+	//fmt.Println("Preprocessing time:", time.Since(startTime))
+	return &reach{
+		graph:                c,
+		grammar:              grammar,
+		worklistIdx:          0,
+		worklist:             []derivation{},
+		derivations:          make([][]*derivation, grammar.NumNonterminals()),
+		seen:                 make(map[derivationKey]bool),
+		tails:                make(map[derivationKey]int32),
+		derivationVertexMap:  make(map[derivationVertex][]*derivation),
+		vertexSCC:            vSCC,
+		reachabilitySCC:      rSCC,
+		recordEdge:           recordEdge,
+		prov:                 newProvenance(),
+	}
+}
+
+// nameToDerivations returns the derivations of every nonterminal
+func (reachData *reach) nameToDerivations() nameToDerivations {
+	res := nameToDerivations{}
+	for name, derivations := range reachData.derivations {
+		if len(derivations) > 0 {
+			res[reachData.grammar.name(int32(name))] = derivations
+		}
+	}
+	return res
+}
+
+func (reachData *reach) run(ctx context.Context) ([]Path, error) {
+	startTime := time.Now()
+	g := reachData.graph
+
+	//Initialization
+	reachData.processBasicRules(g)
+
+	//Main loop
+	paths, err := reachData.allPairsReachabilityMainLoop(ctx, g)
+
+	reachData.stats.Vertices = g.NumVertices()
+	reachData.stats.Edges = g.NumEdges()
+	reachData.stats.Pairs = len(paths)
+	reachData.stats.Derivations = len(reachData.worklist)
+	reachData.stats.Duration = time.Since(startTime)
+	return paths, err
+}
+
+func (reachData *reach) allPairsReachabilityMainLoop(ctx context.Context, g *CSRGraph) ([]Path, error) {
+
+	grammar := reachData.grammar
+	foundPairs := []Path{}
+
+	for {
+		if reachData.demand != nil {
+			reachData.demand.drain(reachData)
+		}
+		if len(reachData.worklist) == reachData.worklistIdx {
+			break
+		}
+
+		if pending := len(reachData.worklist) - reachData.worklistIdx; pending > reachData.stats.PeakWorklist {
+			reachData.stats.PeakWorklist = pending
+		}
+		if reachData.sample != nil && reachData.worklistIdx%_memSampleInterval == 0 {
+			reachData.sample()
+		}
+		if reachData.worklistIdx%_ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return foundPairs, err
+			}
+		}
+
+		worklistItem := reachData.popFromWorklist()
+
+		name := worklistItem.key.name
+		if name == _startID {
+			foundPairs = append(foundPairs, worklistItem.segments[0])
+		}
+
+		reachData.processPrependRules(g, &worklistItem, grammar.prepend[name])
+		reachData.processAppendRules(g, &worklistItem, grammar.append[name])
+		reachData.processInsertRules(g, &worklistItem, grammar.insert[name])
+		reachData.processConcatenateRules(&worklistItem, grammar.concat[name])
+	}
+
+	return foundPairs, nil
+}
+
+func (r *reach) processBasicRules(g *CSRGraph) {
+	for _, edge := range g.GetEdges() {
+		for _, head := range r.grammar.basic[edge.Label] {
+			derivation := r.newDerivation(head, []Path{makePath(edge.From, edge.To)})
+			if r.recordEdge {
+				r.prov.recordEdge(&derivation, edge)
+			}
+			if r.witness == nil {
+				r.addDerivation(&derivation)
+			} else {
+				r.addJustified(&derivation, justification{kind: basicKind, edge: edge})
+			}
+		}
+	}
+}
+
+func (r *reach) processPrependRules(g *CSRGraph, worklistItem *derivation, rules []prependRule) {
+	for _, prependRule := range rules {
+
+		segmentNeedingInEdge := worklistItem.segments[prependRule.PrependIdx]
+		vertexNeedingInEdge := segmentNeedingInEdge.Start
+		candidateVertices := g.inIDs(vertexNeedingInEdge, prependRule.Label)
+
+		for _, candidateID := range candidateVertices {
+			candidateVertex := g.vertices[candidateID]
+			segments := copyPathButReplace(worklistItem.segments, prependRule.PrependIdx,
+				makePath(
+					candidateVertex,
+					worklistItem.segments[prependRule.PrependIdx].End,
+				))
+			derivation := r.newDerivation(prependRule.head, segments)
+			edge := Edge{
+				From:  candidateVertex,
+				To:    vertexNeedingInEdge,
+				Label: prependRule.Label,
+			}
+			if r.recordEdge {
+				r.prov.recordEdge(&derivation, edge)
+				r.prov.recordDerivation(&derivation, worklistItem)
+			}
+			if r.witness == nil {
+				r.addDerivation(&derivation)
+			} else {
+				r.addJustified(&derivation, justification{
+					kind:   prependKind,
+					edge:   edge,
+					idx:    prependRule.PrependIdx,
+					rule:   prependRule.PrependRule,
+					bodies: []derivationKey{worklistItem.key},
+				})
+			}
+		}
+	}
+}
+
+func (r *reach) processAppendRules(g *CSRGraph, worklistItem *derivation, rules []appendRule) {
+	for _, appendRule := range rules {
+
+		segmentNeedingOutEdge := worklistItem.segments[appendRule.AppendIdx]
+		vertexNeedingOutEdge := segmentNeedingOutEdge.End
+		candidateVertices := g.outIDs(vertexNeedingOutEdge, appendRule.Label)
+
+		for _, candidateID := range candidateVertices {
+			candidateVertex := g.vertices[candidateID]
+			segments := copyPathButReplace(worklistItem.segments, appendRule.AppendIdx,
+				makePath(
+					worklistItem.segments[appendRule.AppendIdx].Start,
+					candidateVertex,
+				))
+			derivation := r.newDerivation(appendRule.head, segments)
+			edge := Edge{
+				From:  vertexNeedingOutEdge,
+				To:    candidateVertex,
+				Label: appendRule.Label,
+			}
+			if r.recordEdge {
+				r.prov.recordEdge(&derivation, edge)
+				r.prov.recordDerivation(&derivation, worklistItem)
+			}
+			if r.witness == nil {
+				r.addDerivation(&derivation)
+			} else {
+				r.addJustified(&derivation, justification{
+					kind:   appendKind,
+					edge:   edge,
+					idx:    appendRule.AppendIdx,
+					rule:   appendRule.AppendRule,
+					bodies: []derivationKey{worklistItem.key},
+				})
+			}
+		}
+	}
+}
+
+func (r *reach) processInsertRules(g *CSRGraph, worklistItem *derivation, rules []insertRule) {
+	for _, insertRule := range rules {
+		for _, edge := range g.GetEdgesWithLabel(insertRule.Label) {
+			derivation := r.newDerivation(insertRule.head,
+				copyPathAndInsert(worklistItem.segments, insertRule.InsertIdx,
+					makePath(
+						edge.From,
+						edge.To,
+					)))
+			if r.recordEdge {
+				r.prov.recordEdge(&derivation, edge)
+				r.prov.recordDerivation(&derivation, worklistItem)
+			}
+			if r.witness == nil {
+				r.addDerivation(&derivation)
+			} else {
+				r.addJustified(&derivation, justification{
+					kind:   insertKind,
+					edge:   edge,
+					idx:    insertRule.InsertIdx,
+					rule:   insertRule.InsertRule,
+					bodies: []derivationKey{worklistItem.key},
+				})
+			}
+		}
+	}
+}
+
+func (r *reach) processConcatenateRules(worklistItem *derivation, uses []concatUse) {
+	for _, use := range uses {
+		segments, derivations := r.findRHS(use.rule, use.idx, worklistItem)
+		for i, ends := range segments {
+			derivation := r.newDerivation(use.rule.head, ends)
+			if r.recordEdge {
+				for j := range derivations[i] {
+					r.prov.recordDerivation(&derivation, &derivations[i][j])
+				}
+			}
+			if r.witness == nil {
+				r.addDerivation(&derivation)
+			} else {
+				bodies := make([]derivationKey, len(derivations[i]))
+				for j := range derivations[i] {
+					bodies[j] = derivations[i][j].key
+				}
+				r.addJustified(&derivation, justification{kind: concatKind, rule: use.rule, bodies: bodies})
+			}
+		}
+	}
+}
+
+func (r *reach) findRHS(rule *concatRule, worklistIdx int, worklistItemSegments *derivation) ([]segments,[][]derivation) {
+
+	if !firstCombinationCheck(rule,worklistItemSegments,worklistIdx) {
+		return []segments{}, [][]derivation{}
+	}
+
+	combinations := []segments{}
+	derivations := [][]derivation{}
+
+	list := []*derivation{}
+	for i := 0; i < len(rule.BodyNames); i++ {
+		list = append(list, &derivation{})
+	}
+	list[worklistIdx]=worklistItemSegments
+
+	var recursive func(int)
+	recursive = func(idx int) {
+		if idx == worklistIdx {
+			idx++;
+		}
+		if idx == len(rule.BodyNames) {
+			res := segments{}
+			for _, t := range rule.TermConcatenation {
+				firstSegment := list[t[0].FromBodyIdx].segments[t[0].FromIndexInBody]
+				lastSegment := list[t[len(t)-1].FromBodyIdx].segments[t[len(t)-1].FromIndexInBody]
+				res = append(res, makePath(firstSegment.Start,lastSegment.End)) 
+			}
+			unList := []derivation{}
+			for _, derivation := range list {
+				unList = append(unList, *derivation)
+			}
+			derivations = append(derivations, unList)
+			combinations = append(combinations, res)
+			return
+		}
+		for _, el := range r.getFilteredDerivations(rule, worklistIdx, worklistItemSegments, idx) {
+			list[idx] = el
+			if !combinationCheck(rule,idx+1,list,worklistIdx) {
+				continue 
+			}
+			recursive(idx+1)
+		}
+	}
+
+	recursive(0)
+
+	return combinations, derivations
+}
+
+func firstCombinationCheck(rule *concatRule, worklistItem *derivation, worklistIdx int) bool {
+	for _, term := range rule.TermConcatenation {
+		for i := 1; i < len(term); i++ {
+			if term[i].FromBodyIdx == worklistIdx && term[i-1].FromBodyIdx == worklistIdx {
+				if worklistItem.segments[term[i].FromIndexInBody].Start !=
+					worklistItem.segments[term[i-1].FromIndexInBody].End {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func combinationCheck(rule *concatRule, size int, list []*derivation, worklistIdx int) bool {
+	for _, term := range rule.TermConcatenation {
+		for i := 1; i < len(term); i++ {
+			if term[i].FromBodyIdx != worklistIdx && term[i].FromBodyIdx >= size {
+				i++
+				continue
+			}
+			if term[i-1].FromBodyIdx != worklistIdx && term[i-1].FromBodyIdx >= size {
+				continue
+			}
+			if list[term[i].FromBodyIdx].segments[term[i].FromIndexInBody].Start !=
+					list[term[i-1].FromBodyIdx].segments[term[i-1].FromIndexInBody].End {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (r *reach) getFilteredDerivations(rule *concatRule, worklistIdx int, worklistItemSegments *derivation, myIdx int) []*derivation {
+	ans := []*derivation{}
+	gotAns := false
+	for _, term := range rule.TermConcatenation {
+		for i := 1; i < len(term); i++ {
+			if term[i-1].FromBodyIdx == worklistIdx && term[i].FromBodyIdx == myIdx {
+				worklistVertex := worklistItemSegments.segments[term[i-1].FromIndexInBody].End
+				startKey := derivationVertex{
+					name: rule.bodies[myIdx],
+					dimension: term[i].FromIndexInBody,
+					start: true,
+					vertex: worklistVertex,
+				}
+				if !gotAns || len(r.derivationVertexMap[startKey]) < len(ans) {
+					gotAns = true
+					ans = r.derivationVertexMap[startKey]
+				}
+			}
+
+			if term[i-1].FromBodyIdx == myIdx && term[i].FromBodyIdx == worklistIdx {
+				worklistVertex := worklistItemSegments.segments[term[i].FromIndexInBody].Start
+				endKey := derivationVertex{
+					name: rule.bodies[myIdx],
+					dimension: term[i-1].FromIndexInBody,
+					start: false,
+					vertex: worklistVertex,
+				}
+				if !gotAns || len(r.derivationVertexMap[endKey]) < len(ans) {
+					gotAns = true
+					ans = r.derivationVertexMap[endKey]
+				}
+			}
+
+		}
+	}
+	if gotAns {
+		return ans
+	} else {
+		return r.derivations[rule.bodies[myIdx]]
+	}	
+}
+
+func (r ConcatenateRule) bodyContains(ruleName string) bool {
+	return slices.Contains(r.BodyNames, ruleName)
+}
+
+func copyPathButReplace(toCopy []Path, idxToReplace int, replacement Path) []Path {
+	segments := []Path{}
+	for i, segment := range toCopy {
+		if i == idxToReplace {
+			segments = append(segments, replacement)
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+func copyPathAndInsert(toCopy []Path, idxToInsert int, insert Path) []Path {
+	segments := []Path{}
+	for i, segment := range toCopy {
+		if i == idxToInsert {
+			segments = append(segments, insert)
+		}
+		segments = append(segments, segment)
+	}
+	if idxToInsert == len(toCopy) {
+		segments = append(segments, insert)
+	}
+	return segments
+}
+
+func (r *reach) popFromWorklist() derivation {
+	r.worklistIdx++
+	return r.worklist[r.worklistIdx-1]
+}
+//If v reaches u
+func (r *reach) reaches(v Vertex, u Vertex) bool{
+	return r.reachabilitySCC.reaches(int(r.vertexSCC[r.graph.ids[v]]), int(r.vertexSCC[r.graph.ids[u]]))
+}
+
+func (r *reach) validReachability(toAdd *derivation)  bool {
+	for i, _ := range toAdd.segments {
+		if i > 0 && !r.reaches(toAdd.segments[i-1].End,toAdd.segments[i].Start) {
+			return false
+		}
+	}
+	
+	return true
+}
+
+// addDerivation adds toAdd to the worklist, false if it was seen already
+// or cannot be part of a path
+func (r *reach) addDerivation(toAdd *derivation) bool {
+	if !r.validReachability(toAdd) {
+		return false
+	}
+	if r.seen[toAdd.key] {
+		return false
+	}
+	if r.demand != nil && !r.demand.admits(toAdd) {
+		return false
+	}
+
+	for i, segment := range toAdd.segments {
+		startKey := derivationVertex{
+			name: toAdd.key.name,
+			dimension: i,
+			start: true,
+			vertex: segment.Start,
+		}
+		endKey := derivationVertex{
+			name: toAdd.key.name,
+			dimension: i,
+			start: false,
+			vertex: segment.End,
+		}
+		if _, ok := r.derivationVertexMap[startKey]; !ok {
+			r.derivationVertexMap[startKey] = []*derivation{}
+		}
+		if _, ok := r.derivationVertexMap[endKey]; !ok {
+			r.derivationVertexMap[endKey] = []*derivation{}
+		}
+		r.derivationVertexMap[startKey] = append(r.derivationVertexMap[startKey], toAdd)
+		r.derivationVertexMap[endKey] = append(r.derivationVertexMap[endKey], toAdd)
+	}
+
+	r.worklist = append(r.worklist, *toAdd)
+
+	r.derivations[toAdd.key.name] = append(r.derivations[toAdd.key.name], toAdd)
+	r.seen[toAdd.key] = true
+	if r.demand != nil {
+		r.demand.supply(r, toAdd)
+	}
+	return true
+}
+
+// addJustified is addDerivation recording j as the justification of toAdd.
+// A derivation parked by a goal-directed run keeps its first justification
+// until it is admitted, see demand.drain.
+func (r *reach) addJustified(toAdd *derivation, j justification) {
+	if r.addDerivation(toAdd) {
+		r.witness[toAdd.key] = j
+	} else if r.demand != nil {
+		r.demand.justify(toAdd.key, j)
+	}
+}
+
+func (p Path) sameEnds(p2 Path) bool {
+	return p.Start == p2.Start && p.End == p2.End
+}
+
+func (c *CompiledGrammar) derivationString(d derivation) string {
+	segmentStringList := []string{}
+	for _, el := range d.segments {
+		segmentStringList = append(segmentStringList, el.String())
+	}
+	return fmt.Sprintf("%s(%s)", c.name(d.key.name), strings.Join(segmentStringList, ", "))
+}
+
+func makePath(start Vertex, end Vertex) Path {
+	return Path{Start: start, End: end}
+}
+
+func (p Path) String() string {
+	return fmt.Sprintf("[%d %d]", p.Start, p.End)
+}
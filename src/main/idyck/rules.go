@@ -1,4 +1,4 @@
-package main
+package idyck
 
 import (
 	"fmt"
@@ -137,7 +137,9 @@ func (r ConcatenateRule) String() string {
 	bodyIdxToLength := make([]int, len(r.BodyNames))
 	for _, termConcatenator := range r.TermConcatenation {
 		for _, termIdentifier := range termConcatenator {
-			bodyIdxToLength[termIdentifier.FromBodyIdx] += 1
+			if termIdentifier.FromIndexInBody >= bodyIdxToLength[termIdentifier.FromBodyIdx] {
+				bodyIdxToLength[termIdentifier.FromBodyIdx] = termIdentifier.FromIndexInBody + 1
+			}
 		}
 	}
 	bodyNaming := [][]string{}
@@ -0,0 +1,150 @@
+package idyck
+
+// sccReachability is the reflexive transitive closure of the condensation
+// of a graph. Components are numbered in reverse topological order, so a
+// component only reaches components with a smaller or equal number and row
+// i is a bitset over the components 0..i.
+type sccReachability struct {
+	rows [][]uint64
+}
+
+// reaches reports whether component i reaches component j
+func (r *sccReachability) reaches(i, j int) bool {
+	if j > i {
+		return false
+	}
+	return r.rows[i][j>>6]&(1<<(uint(j)&63)) != 0
+}
+
+// findSccs returns the strongly connected component of every vertex and
+// the reachability between the components
+func (g *Graph) findSccs() (map[Vertex]int, *sccReachability) {
+	c := NewCSRGraph(g)
+	scc, reach := c.findSccs()
+	vertexToSCC := make(map[Vertex]int, len(scc))
+	for id, s := range scc {
+		vertexToSCC[c.vertices[id]] = int(s)
+	}
+	return vertexToSCC, reach
+}
+
+// findSccs is Graph.findSccs on dense ids: the component of vertex id is
+// scc[id]. Tarjan's algorithm runs with an explicit stack, so long chains
+// do not grow the goroutine stack.
+func (c *CSRGraph) findSccs() ([]int32, *sccReachability) {
+	n := len(c.vertices)
+	index := int32(0)
+	vertexIndex := make([]int32, n)
+	vertexLowlink := make([]int32, n)
+	vertexOnStack := make([]bool, n)
+	for v := range vertexIndex {
+		vertexIndex[v] = -1
+	}
+
+	sccCount := 0
+	vertexToSCC := make([]int32, n)
+
+	S := []int32{}
+
+	//a frame is a vertex and the position of its next out edge
+	type frame struct {
+		v    int32
+		next int32
+	}
+	calls := []frame{}
+	visit := func(v int32) {
+		vertexIndex[v] = index
+		vertexLowlink[v] = index
+		index++
+		S = append(S, v)
+		vertexOnStack[v] = true
+		calls = append(calls, frame{v, c.out.offsets[v]})
+	}
+
+	for root := 0; root < n; root++ {
+		if vertexIndex[root] >= 0 {
+			continue
+		}
+		visit(int32(root))
+		for len(calls) > 0 {
+			top := &calls[len(calls)-1]
+			v := top.v
+			if top.next < c.out.offsets[v+1] {
+				w := c.out.adj[top.next]
+				top.next++
+				if vertexIndex[w] < 0 {
+					visit(w)
+				} else if vertexOnStack[w] && vertexIndex[w] < vertexLowlink[v] {
+					vertexLowlink[v] = vertexIndex[w]
+				}
+				continue
+			}
+
+			//all out edges of v are done
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				parent := calls[len(calls)-1].v
+				if vertexLowlink[v] < vertexLowlink[parent] {
+					vertexLowlink[parent] = vertexLowlink[v]
+				}
+			}
+			if vertexLowlink[v] == vertexIndex[v] {
+				for {
+					w := S[len(S)-1]
+					S = S[:len(S)-1]
+					vertexOnStack[w] = false
+					vertexToSCC[w] = int32(sccCount)
+					if w == v {
+						break
+					}
+				}
+				sccCount++
+			}
+		}
+	}
+
+	return vertexToSCC, c.condensationReachability(vertexToSCC, sccCount)
+}
+
+// condensationReachability computes the rows of sccReachability. The
+// successors of a component have smaller numbers, so their rows are done
+// when it is reached and its row is the union of theirs.
+func (c *CSRGraph) condensationReachability(vertexToSCC []int32, sccCount int) *sccReachability {
+	members := make([][]int32, sccCount)
+	for v, s := range vertexToSCC {
+		members[s] = append(members[s], int32(v))
+	}
+
+	//row i has i/64+1 words, all rows share one allocation
+	words := 0
+	for i := 0; i < sccCount; i++ {
+		words += i>>6 + 1
+	}
+	backing := make([]uint64, words)
+	rows := make([][]uint64, sccCount)
+
+	//lastMerged[j] == i if row j was merged into row i already
+	lastMerged := make([]int, sccCount)
+	for j := range lastMerged {
+		lastMerged[j] = -1
+	}
+	for i := 0; i < sccCount; i++ {
+		row := backing[: i>>6+1 : i>>6+1]
+		backing = backing[i>>6+1:]
+		row[i>>6] |= 1 << (uint(i) & 63)
+		for _, v := range members[i] {
+			for _, w := range c.out.all(v) {
+				j := int(vertexToSCC[w])
+				if j == i || lastMerged[j] == i {
+					continue
+				}
+				lastMerged[j] = i
+				for k, word := range rows[j] {
+					row[k] |= word
+				}
+			}
+		}
+		rows[i] = row
+	}
+	return &sccReachability{rows: rows}
+}
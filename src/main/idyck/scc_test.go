@@ -0,0 +1,182 @@
+package idyck
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// recursiveComponents is the recursive search of splitComponents before it
+// used an explicit stack
+func recursiveComponents(g *Graph) map[Vertex]int {
+	component := map[Vertex]int{}
+	var dfs func(v Vertex, c int)
+	dfs = func(v Vertex, c int) {
+		if _, ok := component[v]; ok {
+			return
+		}
+		component[v] = c
+		for _, w := range g.OutEdgesUnlabeled(v) {
+			dfs(w, c)
+		}
+		for _, w := range g.InEdgesUnlabeled(v) {
+			dfs(w, c)
+		}
+	}
+	for _, v := range g.Vertices() {
+		dfs(v, len(component))
+	}
+	return component
+}
+
+// recursiveSccs is the recursive Tarjan of findSccs before it used an
+// explicit stack
+func recursiveSccs(g *Graph) map[Vertex]int {
+	index := map[Vertex]int{}
+	lowlink := map[Vertex]int{}
+	onStack := map[Vertex]bool{}
+	scc := map[Vertex]int{}
+	S := []Vertex{}
+	count := 0
+	var connect func(v Vertex)
+	connect = func(v Vertex) {
+		index[v], lowlink[v] = len(index), len(index)
+		S = append(S, v)
+		onStack[v] = true
+		for _, w := range g.OutEdgesUnlabeled(v) {
+			if _, ok := index[w]; !ok {
+				connect(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] && index[w] < lowlink[v] {
+				lowlink[v] = index[w]
+			}
+		}
+		if lowlink[v] == index[v] {
+			for {
+				w := S[len(S)-1]
+				S = S[:len(S)-1]
+				onStack[w] = false
+				scc[w] = count
+				if w == v {
+					break
+				}
+			}
+			count++
+		}
+	}
+	for _, v := range g.Vertices() {
+		if _, ok := index[v]; !ok {
+			connect(v)
+		}
+	}
+	return scc
+}
+
+// samePartition reports whether got and want put the same vertices
+// together, whatever the numbering
+func samePartition(got map[Vertex]int, want map[Vertex]int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	gotToWant := map[int]int{}
+	wantToGot := map[int]int{}
+	for v, c := range got {
+		w, ok := want[v]
+		if !ok {
+			return false
+		}
+		if d, ok := gotToWant[c]; ok && d != w {
+			return false
+		}
+		if d, ok := wantToGot[w]; ok && d != c {
+			return false
+		}
+		gotToWant[c], wantToGot[w] = w, c
+	}
+	return true
+}
+
+// reachableFrom returns the vertices reachable from v by any edges
+func reachableFrom(g *Graph, v Vertex) map[Vertex]bool {
+	seen := map[Vertex]bool{v: true}
+	stack := []Vertex{v}
+	for len(stack) > 0 {
+		u := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, w := range g.OutEdgesUnlabeled(u) {
+			if !seen[w] {
+				seen[w] = true
+				stack = append(stack, w)
+			}
+		}
+	}
+	return seen
+}
+
+func componentsOf(components []*Graph) map[Vertex]int {
+	component := map[Vertex]int{}
+	for i, c := range components {
+		for _, v := range c.Vertices() {
+			component[v] = i
+		}
+	}
+	return component
+}
+
+func TestSccsMatchRecursive(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		g := randomGraph(rng, 1+rng.Intn(30), rng.Intn(40), _dyckTestLabels)
+
+		components := g.splitComponents()
+		if got, want := componentsOf(components), recursiveComponents(g); !samePartition(got, want) {
+			t.Errorf("graph %d: components %v, want %v", i, got, want)
+		}
+		edges := 0
+		for _, c := range components {
+			edges += c.NumEdges()
+		}
+		if edges != g.NumEdges() {
+			t.Errorf("graph %d: %d edges in the components, %d in the graph", i, edges, g.NumEdges())
+		}
+
+		scc, reach := g.findSccs()
+		if want := recursiveSccs(g); !samePartition(scc, want) {
+			t.Errorf("graph %d: sccs %v, want %v", i, scc, want)
+		}
+		for _, u := range g.Vertices() {
+			reachable := reachableFrom(g, u)
+			for _, v := range g.Vertices() {
+				if reach.reaches(scc[u], scc[v]) != reachable[v] {
+					t.Errorf("graph %d: %d reaches %d is %v", i, u, v, reachable[v])
+				}
+			}
+		}
+	}
+}
+
+// TestLongChain runs the searches on a chain of a million vertices, which
+// is too deep for the recursive versions. The chain is closed to a cycle,
+// with a million components the rows of the condensation would be
+// quadratic in size.
+func TestLongChain(t *testing.T) {
+	const n = 1000000
+	g := MakeGraph()
+	for v := 0; v < n; v++ {
+		g.AddEdge(Vertex(v), Vertex((v+1)%n), "normal")
+	}
+
+	if components := g.splitComponents(); len(components) != 1 || components[0].NumVertices() != n {
+		t.Errorf("got %d components", len(components))
+	}
+	scc, reach := g.findSccs()
+	for v := 0; v < n; v++ {
+		if scc[Vertex(v)] != 0 {
+			t.Fatalf("vertex %d is in scc %d", v, scc[Vertex(v)])
+		}
+	}
+	if !reach.reaches(0, 0) {
+		t.Errorf("the scc does not reach itself")
+	}
+}
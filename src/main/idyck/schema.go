@@ -0,0 +1,182 @@
+package idyck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// The analyses and the Dyck grammars read the alphabet from the labels:
+// op--N and cp--N open and close parenthesis N, ob--N and cb--N bracket N,
+// and normal is neutral. A LabelSchema declares the alphabet of graphs
+// from other frontends and translates their labels to these.
+//
+// The schema only translates labels on the way in. The analyses still
+// assume the fixed convention: DyckEdges, parseDyckComponentNaive and
+// multiplyByAutomaton read the number N as label[4:], and otherLabel
+// pairs an opening label with its closing one by the first letter.
+// Results carry the translated labels, Restore maps the edges of a witness
+// back.
+
+// LabelPair is the opening and the closing label of a parenthesis or a
+// bracket.
+type LabelPair struct {
+	Open  Label `json:"open"`
+	Close Label `json:"close"`
+}
+
+// LabelSchema declares which labels of a graph open and close which
+// parenthesis and bracket, and which labels are neutral. For valueflow
+// graphs the first bracket is the one of the [s] condition.
+type LabelSchema struct {
+	Parentheses []LabelPair `json:"parentheses"`
+	Brackets    []LabelPair `json:"brackets"`
+	Neutral     []Label     `json:"neutral"`
+}
+
+// ReadLabelSchema reads a schema in JSON, e.g.
+//
+//	{"parentheses": [{"open": "call1", "close": "ret1"}],
+//	 "brackets": [{"open": "store.f", "close": "load.f"}],
+//	 "neutral": ["assign"]}
+//
+// and validates it.
+func ReadLabelSchema(reader io.Reader) (*LabelSchema, error) {
+	dec := json.NewDecoder(reader)
+	dec.DisallowUnknownFields()
+	s := &LabelSchema{}
+	if err := dec.Decode(s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ReadLabelSchemaFile reads and validates the schema in fileName, see
+// ReadLabelSchema.
+func ReadLabelSchemaFile(fileName string) (*LabelSchema, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	s, err := ReadLabelSchema(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return s, nil
+}
+
+// Validate checks that every label is declared once and is not empty, the
+// empty label is the one of the epsilon self-loops.
+func (s *LabelSchema) Validate() error {
+	_, err := s.table()
+	return err
+}
+
+// table maps the labels of the schema to the labels of the analyses
+func (s *LabelSchema) table() (map[Label]Label, error) {
+	res := map[Label]Label{}
+	add := func(label Label, to Label) error {
+		if label == "" {
+			return fmt.Errorf("schema: empty label")
+		}
+		if _, ok := res[label]; ok {
+			return fmt.Errorf("schema: label %q is declared twice", label)
+		}
+		res[label] = to
+		return nil
+	}
+	for _, pairs := range []struct {
+		kind  string
+		pairs []LabelPair
+	}{{"p", s.Parentheses}, {"b", s.Brackets}} {
+		for i, pair := range pairs.pairs {
+			id := strconv.Itoa(i)
+			if err := add(pair.Open, Label("o"+pairs.kind+"--"+id)); err != nil {
+				return nil, err
+			}
+			if err := add(pair.Close, Label("c"+pairs.kind+"--"+id)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, label := range s.Neutral {
+		if err := add(label, "normal"); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// ApplyEdges translates the labels of edges. Epsilon self-loops are kept,
+// a label that is not in the schema is an error.
+func (s *LabelSchema) ApplyEdges(edges []Edge) ([]Edge, error) {
+	table, err := s.table()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Edge, 0, len(edges))
+	for _, e := range edges {
+		if e.From == e.To && len(e.Label) == 0 {
+			res = append(res, e)
+			continue
+		}
+		label, ok := table[e.Label]
+		if !ok {
+			return nil, fmt.Errorf("label %q of edge %d->%d is not in the schema", e.Label, e.From, e.To)
+		}
+		res = append(res, Edge{From: e.From, To: e.To, Label: label})
+	}
+	return res, nil
+}
+
+// Apply returns g with its labels translated, for the analyses. Edges
+// that become equal, e.g. with two neutral labels, are kept once.
+func (s *LabelSchema) Apply(g *Graph) (*Graph, error) {
+	edges, err := s.ApplyEdges(g.edgeList)
+	if err != nil {
+		return nil, err
+	}
+	res := MakeGraph()
+	exists := map[Edge]bool{}
+	for _, e := range edges {
+		if exists[e] || e.From == e.To && len(e.Label) == 0 {
+			continue
+		}
+		exists[e] = true
+		res.AddEdge(e.From, e.To, e.Label)
+	}
+	return res, nil
+}
+
+// Restore gives edges of a graph returned by Apply, e.g. a witness path,
+// the labels they have in g. Neutral labels are not distinguished by the
+// analyses, an edge gets the smallest of those that g has for it.
+func (s *LabelSchema) Restore(g *Graph, edges []Edge) []Edge {
+	table, err := s.table()
+	if err != nil {
+		return edges
+	}
+	res := make([]Edge, 0, len(edges))
+	for _, e := range edges {
+		restored, found := e, false
+		for label, to := range g.outEdges[e.From] {
+			if table[label] != e.Label || found && label > restored.Label {
+				continue
+			}
+			for _, v := range to {
+				if v == e.To {
+					restored, found = Edge{From: e.From, To: e.To, Label: label}, true
+					break
+				}
+			}
+		}
+		res = append(res, restored)
+	}
+	return res
+}
@@ -0,0 +1,140 @@
+package idyck
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestLabelSchemaValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema LabelSchema
+		err    string //"" if valid
+	}{
+		{
+			"valid",
+			LabelSchema{
+				Parentheses: []LabelPair{{"call", "ret"}},
+				Brackets:    []LabelPair{{"store", "load"}},
+				Neutral:     []Label{"assign", "copy"},
+			},
+			"",
+		},
+		{"empty open", LabelSchema{Parentheses: []LabelPair{{"", "ret"}}}, "empty label"},
+		{"empty close", LabelSchema{Brackets: []LabelPair{{"store", ""}}}, "empty label"},
+		{"empty neutral", LabelSchema{Neutral: []Label{""}}, "empty label"},
+		{"open is close", LabelSchema{Parentheses: []LabelPair{{"a", "a"}}}, `"a" is declared twice`},
+		{"twice neutral", LabelSchema{Neutral: []Label{"assign", "assign"}}, `"assign" is declared twice`},
+		{
+			"parenthesis and bracket",
+			LabelSchema{Parentheses: []LabelPair{{"call", "ret"}}, Brackets: []LabelPair{{"ret", "load"}}},
+			`"ret" is declared twice`,
+		},
+		{
+			"bracket and neutral",
+			LabelSchema{Brackets: []LabelPair{{"store", "load"}}, Neutral: []Label{"load"}},
+			`"load" is declared twice`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.schema.Validate()
+			if test.err == "" && err != nil || test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("got %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestApplyEdges(t *testing.T) {
+	s := &LabelSchema{Parentheses: []LabelPair{{"call", "ret"}}, Neutral: []Label{"assign"}}
+	got, err := s.ApplyEdges([]Edge{{1, 1, ""}, {1, 2, "call"}, {2, 3, "assign"}, {3, 4, "ret"}, {4, 4, ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Edge{{1, 1, ""}, {1, 2, "op--0"}, {2, 3, "normal"}, {3, 4, "cp--0"}, {4, 4, ""}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	//the empty label is only the one of epsilon self-loops
+	for _, e := range []Edge{{1, 2, "store"}, {1, 2, ""}} {
+		if _, err := s.ApplyEdges([]Edge{e}); err == nil {
+			t.Errorf("no error for %v", e)
+		}
+	}
+}
+
+// _schemaTest gives custom labels to _dyckTestLabels, two for normal
+var _schemaTest = &LabelSchema{
+	Parentheses: []LabelPair{{"call0", "ret0"}, {"call1", "ret1"}},
+	Brackets:    []LabelPair{{"store", "load"}},
+	Neutral:     []Label{"copy", "assign"},
+}
+
+var _schemaTestLabels = map[Label][]Label{
+	"op--0": {"call0"}, "cp--0": {"ret0"}, "op--1": {"call1"}, "cp--1": {"ret1"},
+	"ob--0": {"store"}, "cb--0": {"load"}, "normal": {"copy", "assign"},
+}
+
+// TestLabelSchemaApply checks that a run on a graph with custom labels
+// gives the pairs of the same graph with the labels of the analyses
+func TestLabelSchemaApply(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	for _, kind := range []Kind{Taint, Valueflow} {
+		for i := 0; i < 10; i++ {
+			g := randomGraph(rng, 8, 16, _dyckTestLabels)
+			custom := MakeGraph()
+			for _, e := range labelledEdges(g) {
+				labels := _schemaTestLabels[e.Label]
+				custom.AddEdge(e.From, e.To, labels[rng.Intn(len(labels))])
+			}
+			applied, err := _schemaTest.Apply(custom)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := NewAnalyzer(Config{Parallelism: 1, Kind: kind}).RunContext(ctx, g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewAnalyzer(Config{Parallelism: 1, Kind: kind}).RunContext(ctx, applied)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, stage := range want.Stages {
+				if fmt.Sprint(got.Paths(stage)) != fmt.Sprint(want.Paths(stage)) {
+					t.Errorf("%s graph %d, %s: got %v, want %v", kind, i, stage, got.Paths(stage), want.Paths(stage))
+				}
+			}
+		}
+	}
+}
+
+func TestLabelSchemaRestore(t *testing.T) {
+	g := MakeGraph()
+	g.AddEdge(1, 2, "copy")
+	g.AddEdge(1, 2, "assign")
+	g.AddEdge(2, 3, "call0")
+	g.AddEdge(3, 4, "store")
+	g.AddEdge(4, 5, "load")
+	g.AddEdge(5, 6, "ret0")
+	applied, err := _schemaTest.Apply(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := applied.NumEdges(); n != 5 {
+		t.Errorf("the parallel neutral edges are not merged, %d edges", n)
+	}
+	witness, err := NewAnalyzer(Config{Parallelism: 1}).Witness(context.Background(), applied, makePath(1, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := _schemaTest.Restore(g, witness)
+	want := []Edge{{1, 2, "assign"}, {2, 3, "call0"}, {3, 4, "store"}, {4, 5, "load"}, {5, 6, "ret0"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
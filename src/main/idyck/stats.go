@@ -0,0 +1,72 @@
+package idyck
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// the heap is sampled every _memSampleInterval worklist items, reading the
+// memory statistics stops the world
+const _memSampleInterval = 1 << 14
+
+// ReachStats describes one all-pairs reachability computation.
+type ReachStats struct {
+	Vertices     int //of the graph, without epsilon self-loops for Edges
+	Edges        int
+	Pairs        int //derived from the start nonterminal
+	Derivations  int //added to the worklist
+	PeakWorklist int //largest number of derivations waiting in the worklist
+	Duration     time.Duration
+}
+
+// MemStats is the memory used while running a stage.
+type MemStats struct {
+	Allocs     uint64 //heap objects allocated
+	AllocBytes uint64 //bytes allocated
+	PeakHeap   uint64 //largest heap in use among the samples
+}
+
+// memSampler measures allocations since its creation and samples the heap.
+// The workers of a stage share its sampler.
+type memSampler struct {
+	mu    sync.Mutex
+	start runtime.MemStats
+	last  runtime.MemStats
+	peak  uint64
+}
+
+func newMemSampler() *memSampler {
+	m := &memSampler{}
+	runtime.ReadMemStats(&m.start)
+	m.last = m.start
+	m.peak = m.start.HeapAlloc
+	return m
+}
+
+func (m *memSampler) sample() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	runtime.ReadMemStats(&m.last)
+	if m.last.HeapAlloc > m.peak {
+		m.peak = m.last.HeapAlloc
+	}
+}
+
+// stats as of the last sample
+func (m *memSampler) stats() MemStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return MemStats{
+		Allocs:     m.last.Mallocs - m.start.Mallocs,
+		AllocBytes: m.last.TotalAlloc - m.start.TotalAlloc,
+		PeakHeap:   m.peak,
+	}
+}
+
+// sampleMem samples the heap if a stage is being measured
+func (a *Analyzer) sampleMem() {
+	if a.mem != nil {
+		a.mem.sample()
+	}
+}
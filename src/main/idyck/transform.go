@@ -0,0 +1,311 @@
+package idyck
+
+import (
+	"fmt"
+)
+
+// Grammar passes run before the normal form transformation, ported from
+// mcfg_rank.py and mcfg_permute.py. The engine joins all body atoms of a
+// concatenate rule at once, so rules of high rank are reduced to rank 2.
+// The normal form assumes non-deleting, non-permuting rules.
+
+// prepareRules makes the rules non-deleting and non-permuting and reduces
+// them to rank at most 2. Grammars that are already in this shape are
+// returned unchanged.
+func prepareRules(mcfg []mcfgRule, dim map[string]int) ([]mcfgRule, map[string]int) {
+	if !nonPermutingRules(mcfg) {
+		mcfg, dim = permuteRules(mcfg)
+	}
+	mcfg = chomskyRules(mcfg, dim)
+	mcfg = rank2Rules(mcfg, dim)
+	//reducing the rank can introduce permutations
+	if !nonPermutingRules(mcfg) {
+		mcfg, dim = permuteRules(mcfg)
+	}
+	return mcfg, dim
+}
+
+// ReduceRank returns an equivalent grammar in which every rule has at most
+// two body atoms. Rules of dimension 1 keep dimension 1 (cf. Chomsky normal
+// form), other rules introduce nonterminals of larger dimension.
+func (g *GrammarAST) ReduceRank() (*GrammarAST, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	rules := g.lower()
+	dim, err := mcfgDimensions(rules)
+	if err != nil {
+		return nil, err
+	}
+	rules = chomskyRules(rules, dim)
+	return raiseRules(rank2Rules(rules, dim)), nil
+}
+
+// NonPermuting returns an equivalent non-deleting, non-permuting grammar.
+// Nonterminals are copied for every order in which their arguments are
+// used, rules not reachable from the start symbol are dropped.
+func (g *GrammarAST) NonPermuting() (*GrammarAST, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	rules, _ := permuteRules(g.lower())
+	return raiseRules(rules), nil
+}
+
+// raiseRules is the inverse of lower, without positions
+func raiseRules(mcfg []mcfgRule) *GrammarAST {
+	g := &GrammarAST{Rules: []RuleAST{}}
+	for _, rule := range mcfg {
+		r := RuleAST{
+			Head: HeadAST{Name: rule.head.nterm, Args: []Word{}},
+			Body: []BodyAST{},
+		}
+		for _, w := range rule.head.args {
+			word := Word{}
+			for _, x := range w {
+				word = append(word, Symbol{Kind: symbolKind(x), Name: x})
+			}
+			r.Head.Args = append(r.Head.Args, word)
+		}
+		for _, atom := range rule.body {
+			b := BodyAST{Name: atom.nterm, Args: []Symbol{}}
+			for _, x := range atom.args {
+				b.Args = append(b.Args, Symbol{Kind: symbolKind(x), Name: x})
+			}
+			r.Body = append(r.Body, b)
+		}
+		g.Rules = append(g.Rules, r)
+	}
+	return g
+}
+
+// findVar returns the first variable in word, or len(word)
+func findVar(word []string) int {
+	for i, x := range word {
+		if isVar(x) {
+			return i
+		}
+	}
+	return len(word)
+}
+
+// CHOMSKY: A(w1 X w2) :- ..., Aj(X), ... becomes
+// A(w1 X Z) :- Aj(X), A1(Z). and A1(w2) :- ... (the other atoms)
+// for rules of dimension 1 with more than two atoms of dimension 1
+func chomskyRules(mcfg []mcfgRule, dim map[string]int) []mcfgRule {
+	result := []mcfgRule{}
+	mcfg = append([]mcfgRule{}, mcfg...)
+	for len(mcfg) > 0 {
+		rule := popRule(&mcfg)
+		A := rule.head.nterm
+		body := rule.body
+		if dim[A] > 1 || len(body) <= 2 {
+			result = append(result, rule)
+			continue
+		}
+		maxDim := 0
+		for _, atom := range body {
+			if dim[atom.nterm] > maxDim {
+				maxDim = dim[atom.nterm]
+			}
+		}
+		if maxDim > 1 {
+			result = append(result, rule)
+			continue
+		}
+		w := rule.head.args[0]
+		i := findVar(w)
+		if i == len(w) {
+			result = append(result, rule)
+			continue
+		}
+		X := w[i]
+		j, _ := findRHS(X, body)
+		Aj := newNterm(dim, 1, A)
+		Z := freshVars(1, []string{X})[0]
+		head1 := mcfgHead{A, [][]string{concatSymbols(w[:i+1], []string{Z})}}
+		body1 := []mcfgBody{body[j], {Aj, []string{Z}}}
+		result = append(result, mcfgRule{head1, body1})
+		head2 := mcfgHead{Aj, [][]string{w[i+1:]}}
+		body2 := concatBodies(body[:j], body[j+1:])
+		mcfg = append(mcfg, mcfgRule{head2, body2})
+	}
+	return result
+}
+
+// RANK2: A(...) :- B1(...), ..., Bn-1(X1), Bn(X2). becomes
+// A0(X1, X2) :- Bn-1(X1), Bn(X2). and A(...) :- B1(...), ..., A0(X1 X2).
+// until at most two atoms are left
+func rank2Rules(mcfg []mcfgRule, dim map[string]int) []mcfgRule {
+	result := []mcfgRule{}
+	for _, rule := range mcfg {
+		body := copyBodies(rule.body)
+		A := rule.head.nterm
+		for len(body) > 2 {
+			b1 := body[len(body)-2]
+			b2 := body[len(body)-1]
+			body = body[:len(body)-2]
+			X := concatSymbols(b1.args, b2.args)
+			A0 := newNterm(dim, len(X), A)
+			body = append(body, mcfgBody{A0, X})
+			result = append(result, mcfgRule{mcfgHead{A0, varWords(X)}, []mcfgBody{b1, b2}})
+		}
+		result = append(result, mcfgRule{rule.head, body})
+	}
+	return result
+}
+
+func concatBodies(parts ...[]mcfgBody) []mcfgBody {
+	res := []mcfgBody{}
+	for _, part := range parts {
+		res = append(res, part...)
+	}
+	return res
+}
+
+// the variables of the head, from left to right
+func headVars(args [][]string) []string {
+	res := []string{}
+	for _, w := range args {
+		for _, x := range w {
+			if isVar(x) {
+				res = append(res, x)
+			}
+		}
+	}
+	return res
+}
+
+// getPermutation returns the positions in rvars of the variables in lvars,
+// in the order of lvars. Variables missing in rvars are skipped.
+func getPermutation(lvars []string, rvars []string) []int {
+	perm := []int{}
+	for _, x := range lvars {
+		for i, y := range rvars {
+			if x == y {
+				perm = append(perm, i)
+				break
+			}
+		}
+	}
+	return perm
+}
+
+// a rule is non-deleting and non-permuting iff the permutation of each
+// body atom is the identity
+func nonPermuting(rule mcfgRule) bool {
+	lvars := headVars(rule.head.args)
+	for _, atom := range rule.body {
+		perm := getPermutation(lvars, atom.args)
+		if len(perm) != len(atom.args) {
+			return false
+		}
+		for i, p := range perm {
+			if i != p {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func nonPermutingRules(mcfg []mcfgRule) bool {
+	for _, rule := range mcfg {
+		if !nonPermuting(rule) {
+			return false
+		}
+	}
+	return true
+}
+
+// productiveRules drops the rules with a body atom that derives nothing
+func productiveRules(mcfg []mcfgRule) []mcfgRule {
+	productive := map[string]bool{}
+	usable := func(rule mcfgRule) bool {
+		for _, atom := range rule.body {
+			if !productive[atom.nterm] {
+				return false
+			}
+		}
+		return true
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range mcfg {
+			if !productive[rule.head.nterm] && usable(rule) {
+				productive[rule.head.nterm] = true
+				changed = true
+			}
+		}
+	}
+	result := []mcfgRule{}
+	for _, rule := range mcfg {
+		if usable(rule) {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
+
+// a nonterminal whose arguments are used in the order perm
+type permTask struct {
+	nterm string
+	perm  string
+}
+
+func makePermTask(nterm string, perm []int) permTask {
+	return permTask{nterm, fmt.Sprint(perm)}
+}
+
+// permuteRules makes the rules non-deleting and non-permuting, starting
+// from S(X). Each body atom B(x1, ..., xn) is replaced by a copy of B whose
+// arguments are the xi used in the head, in the order of the head. Atoms
+// without such arguments only require B to be productive, so they are
+// dropped after removing unproductive rules.
+func permuteRules(mcfg []mcfgRule) ([]mcfgRule, map[string]int) {
+	mcfg = productiveRules(mcfg)
+	task := makePermTask(_startNonTerminal, []int{0})
+	perms := map[permTask][]int{task: {0}}
+	work := []permTask{task}
+	done := map[permTask]string{task: _startNonTerminal}
+	dim := map[string]int{_startNonTerminal: 1}
+	result := []mcfgRule{}
+	for len(work) > 0 {
+		task := work[0]
+		work = work[1:]
+		perm := perms[task]
+		for _, rule := range mcfg {
+			if rule.head.nterm != task.nterm {
+				continue
+			}
+			args := [][]string{}
+			for _, i := range perm {
+				args = append(args, rule.head.args[i])
+			}
+			newHead := mcfgHead{done[task], args}
+			newBody := []mcfgBody{}
+			lvars := headVars(args)
+			for _, atom := range rule.body {
+				rperm := getPermutation(lvars, atom.args)
+				if len(rperm) == 0 {
+					continue
+				}
+				ntask := makePermTask(atom.nterm, rperm)
+				nterm, ok := done[ntask]
+				if !ok {
+					nterm = newNterm(dim, len(rperm), atom.nterm)
+					done[ntask] = nterm
+					perms[ntask] = rperm
+					work = append(work, ntask)
+				}
+				rargs := []string{}
+				for _, i := range rperm {
+					rargs = append(rargs, atom.args[i])
+				}
+				newBody = append(newBody, mcfgBody{nterm, rargs})
+			}
+			result = append(result, mcfgRule{newHead, newBody})
+		}
+	}
+	return result, dim
+}
@@ -0,0 +1,171 @@
+package idyck
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// randomGraph returns a graph with n vertices and m random edges labeled
+// from labels
+func randomGraph(rng *rand.Rand, n int, m int, labels []Label) *Graph {
+	g := MakeGraph()
+	for i := 0; i < m; i++ {
+		g.AddEdge(Vertex(rng.Intn(n)), Vertex(rng.Intn(n)), labels[rng.Intn(len(labels))])
+	}
+	return g
+}
+
+// naivePairs evaluates a grammar in the surface syntax on g by brute force,
+// whatever its rank and shape: a fact of A is a segment per argument, and a
+// rule derives its head from every combination of facts of its body. Unlike
+// the language of a deleting rule, the facts also require the deleted
+// arguments to be paths of g, so deleting grammars are not compared.
+func naivePairs(g *Graph, ast *GrammarAST) []Path {
+	facts := map[string][][]Path{}
+	seen := map[string]bool{}
+	add := func(name string, fact []Path) bool {
+		key := fmt.Sprint(name, fact)
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+		facts[name] = append(facts[name], fact)
+		return true
+	}
+
+	//segments of word from any vertex, with vars bound to segments
+	spans := func(word Word, vars map[string]Path) []Path {
+		res := []Path{}
+		for _, start := range g.Vertices() {
+			cur := map[Vertex]bool{start: true}
+			for _, s := range word {
+				next := map[Vertex]bool{}
+				for v := range cur {
+					switch s.Kind {
+					case Epsilon:
+						next[v] = true
+					case Variable:
+						if vars[s.Name].Start == v {
+							next[vars[s.Name].End] = true
+						}
+					case Terminal:
+						for _, u := range g.OutEdges(v, Label(s.Name)) {
+							next[u] = true
+						}
+					}
+				}
+				cur = next
+			}
+			for v := range cur {
+				res = append(res, Path{Start: start, End: v})
+			}
+		}
+		return res
+	}
+
+	var derive func(rule RuleAST, i int, vars map[string]Path, fact []Path) bool
+	derive = func(rule RuleAST, i int, vars map[string]Path, fact []Path) bool {
+		if i < len(rule.Body) {
+			changed := false
+			body := rule.Body[i]
+			for _, f := range facts[body.Name] {
+				for k, x := range body.Args {
+					vars[x.Name] = f[k]
+				}
+				changed = derive(rule, i+1, vars, fact) || changed
+			}
+			return changed
+		}
+		if len(fact) == len(rule.Head.Args) {
+			return add(rule.Head.Name, append([]Path{}, fact...))
+		}
+		changed := false
+		for _, span := range spans(rule.Head.Args[len(fact)], vars) {
+			changed = derive(rule, i, vars, append(fact, span)) || changed
+		}
+		return changed
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range ast.Rules {
+			changed = derive(rule, 0, map[string]Path{}, []Path{}) || changed
+		}
+	}
+	res := []Path{}
+	for _, f := range facts[_startNonTerminal] {
+		res = append(res, f[0])
+	}
+	SortPaths(res)
+	return res
+}
+
+func TestTransformsPreserveLanguage(t *testing.T) {
+	grammars := map[string]string{
+		"rank3": `S(X Y Z) :- A(X), B(Y), C(Z).
+			A(a). A(X a) :- A(X).
+			B(b).
+			C(c). C(X Y) :- C(X), B(Y).`,
+		"rank3 of dimension 2": `S(X1 Y1 Z1 X2 Y2 Z2) :- A(X1, X2), B(Y1, Y2), C(Z1, Z2).
+			A(a, b).
+			B(c, d). B(X c, Y d) :- B(X, Y).
+			C(X, Y) :- A(X, Y).`,
+		"permuting": `S(X Y) :- A(Y, X).
+			A(a X, b Y) :- A(X, Y).
+			A(c, d).`,
+		"permuting rank3": `S(Z X Y W) :- A(Y, X), B(Z), C(W).
+			A(X a, Y) :- A(Y, X).
+			A(b, c).
+			B(d). B(eps).
+			C(d). C(X a) :- C(X).`,
+	}
+	labels := []Label{"a", "b", "c", "d"}
+	for name, text := range grammars {
+		t.Run(name, func(t *testing.T) {
+			ast, err := ParseMCFG(strings.NewReader(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ranked, err := ast.ReduceRank()
+			if err != nil {
+				t.Fatal(err)
+			}
+			nonPermuting, err := ast.NonPermuting()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := nonPermuting.Validate(); err != nil || len(nonPermuting.Check()) != 0 {
+				t.Fatalf("NonPermuting: %v", nonPermuting.Check())
+			}
+			m, err := ast.MCFG()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Rank() > 2 {
+				t.Errorf("MCFG() has rank %d", m.Rank())
+			}
+
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 40; i++ {
+				g := randomGraph(rng, 4, 12, labels)
+				want := naivePairs(g, ast)
+				for _, transformed := range []*GrammarAST{ranked, nonPermuting} {
+					if got := naivePairs(g, transformed); fmt.Sprint(got) != fmt.Sprint(want) {
+						t.Fatalf("%v\n%s\ngot %v, want %v", g.GetEdges(), transformed, got, want)
+					}
+				}
+				got, _, err := AllPairsReachability(context.Background(), g, &m, false, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				SortPaths(got)
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("%v\nMCFG() got %v, want %v", g.GetEdges(), got, want)
+				}
+			}
+		})
+	}
+}
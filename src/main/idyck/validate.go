@@ -0,0 +1,314 @@
+package idyck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Static semantics of MCFGs (cf. mcfg_check.py)
+//
+// errors:
+// each nonterminal has the same dimension in all rules
+// all arguments in the body are unique variables
+// all variables in the head occur at most once
+// all variables in the head occur in the body
+// each nonterminal that occurs in some body is defined in some head
+// there exists a start symbol S, and it has dimension 1
+//
+// warnings:
+// all variables in the body occur in the head (non-deleting)
+// variables of each body atom occur in the head in the same order (non-permuting)
+
+// Violation is one problem found in a grammar. Rule is the offending rule,
+// empty for problems of the whole grammar.
+type Violation struct {
+	Pos     Pos
+	Rule    string
+	Msg     string
+	Warning bool
+}
+
+func (v Violation) String() string {
+	s := v.Msg
+	if v.Rule != "" {
+		s = fmt.Sprintf("%s <-- %s", v.Rule, v.Msg)
+	}
+	if v.Pos.Line > 0 {
+		s = fmt.Sprintf("%v: %s", v.Pos, s)
+	}
+	if v.Warning {
+		s = "Warning: " + s
+	}
+	return s
+}
+
+// ValidationError lists every violation of a grammar that has at least one
+// error; warnings are included.
+type ValidationError []Violation
+
+func (e ValidationError) Error() string {
+	errors := 0
+	lines := []string{}
+	for _, v := range e {
+		if !v.Warning {
+			errors++
+		}
+		lines = append(lines, v.String())
+	}
+	return fmt.Sprintf("Detected %d semantic errors\n%s", errors, strings.Join(lines, "\n"))
+}
+
+func validationError(violations []Violation) error {
+	for _, v := range violations {
+		if !v.Warning {
+			return ValidationError(violations)
+		}
+	}
+	return nil
+}
+
+type violations []Violation
+
+func (vs *violations) add(pos Pos, rule fmt.Stringer, warning bool, format string, a ...any) {
+	ruleString := ""
+	if rule != nil {
+		ruleString = rule.String()
+	}
+	*vs = append(*vs, Violation{
+		Pos:     pos,
+		Rule:    ruleString,
+		Msg:     fmt.Sprintf(format, a...),
+		Warning: warning,
+	})
+}
+
+// checkStart checks the start symbol, rule is its first rule if any
+func checkStart(vs *violations, dim map[string]int, pos Pos, rule fmt.Stringer) {
+	d, ok := dim[_startNonTerminal]
+	if !ok {
+		vs.add(Pos{}, nil, false, "There should be a start symbol '%s'", _startNonTerminal)
+	} else if d != 1 {
+		vs.add(pos, rule, false, `Start symbol "%s" has arity %d, not 1`, _startNonTerminal, d)
+	}
+}
+
+// Check returns all violations of g, errors and warnings.
+func (g *GrammarAST) Check() []Violation {
+	vs := violations{}
+
+	dim := make(map[string]int)
+	for _, rule := range g.Rules {
+		head := rule.Head
+		if d, ok := dim[head.Name]; ok && d != len(head.Args) {
+			vs.add(head.Pos, rule, false, "head %s cannot have dimension %d and %d", head.Name, d, len(head.Args))
+			continue
+		}
+		dim[head.Name] = len(head.Args)
+	}
+
+	for _, rule := range g.Rules {
+		for _, body := range rule.Body {
+			d, ok := dim[body.Name]
+			if !ok {
+				vs.add(body.Pos, rule, false, "%s in body has no defining head", body.Name)
+			} else if d != len(body.Args) {
+				vs.add(body.Pos, rule, false, "%s in body has dimension %d, but the head used %d", body.Name, len(body.Args), d)
+			}
+		}
+		checkRuleVars(&vs, rule)
+	}
+
+	var start fmt.Stringer
+	startPos := Pos{}
+	for _, rule := range g.Rules {
+		if rule.Head.Name == _startNonTerminal {
+			start, startPos = rule, rule.Head.Pos
+			break
+		}
+	}
+	checkStart(&vs, dim, startPos, start)
+
+	return vs
+}
+
+// Validate returns a ValidationError if g has at least one error.
+func (g *GrammarAST) Validate() error {
+	return validationError(g.Check())
+}
+
+func checkRuleVars(vs *violations, rule RuleAST) {
+	bodyVars := map[string]bool{}
+	for _, body := range rule.Body {
+		for _, arg := range body.Args {
+			if arg.Kind != Variable {
+				vs.add(arg.Pos, rule, false, "body contains non-variable %s (all variables must be capitalized)", arg.Name)
+			} else if bodyVars[arg.Name] {
+				vs.add(arg.Pos, rule, false, "body is non-linear, %s occurs twice", arg.Name)
+			}
+			bodyVars[arg.Name] = true
+		}
+	}
+
+	headVars := []string{}
+	seen := map[string]bool{}
+	for _, w := range rule.Head.Args {
+		for _, x := range w {
+			if x.Kind != Variable {
+				continue
+			}
+			if !bodyVars[x.Name] {
+				vs.add(x.Pos, rule, false, "head contains free variable %s", x.Name)
+			} else if seen[x.Name] {
+				vs.add(x.Pos, rule, false, "head is non-linear, %s occurs twice", x.Name)
+			}
+			seen[x.Name] = true
+			headVars = append(headVars, x.Name)
+		}
+	}
+
+	for _, body := range rule.Body {
+		for _, arg := range body.Args {
+			if arg.Kind == Variable && !seen[arg.Name] {
+				vs.add(arg.Pos, rule, true, "rule is deleting, %s does not occur in the head", arg.Name)
+			}
+		}
+	}
+
+	for _, body := range rule.Body {
+		vars := []string{}
+		for _, arg := range body.Args {
+			if seen[arg.Name] {
+				vars = append(vars, arg.Name)
+			}
+		}
+		for _, x := range headVars {
+			if len(vars) > 0 && x == vars[0] {
+				vars = vars[1:]
+			}
+		}
+		if len(vars) != 0 {
+			vs.add(body.Pos, rule, true, "rule is permuting")
+		}
+	}
+}
+
+// Check returns all violations of the rules of m.
+func (m MCFG) Check() []Violation {
+	vs := violations{}
+	dim := make(map[string]int)
+
+	head := func(name string, d int, rule fmt.Stringer) {
+		if old, ok := dim[name]; ok && old != d {
+			vs.add(Pos{}, rule, false, "head %s cannot have dimension %d and %d", name, old, d)
+			return
+		}
+		dim[name] = d
+	}
+	for _, rule := range m.BasicRules {
+		head(rule.HeadName, 1, rule)
+	}
+	for _, rule := range m.PrependRules {
+		head(rule.HeadName, rule.Terms, rule)
+	}
+	for _, rule := range m.AppendRules {
+		head(rule.HeadName, rule.Terms, rule)
+	}
+	for _, rule := range m.InsertRules {
+		head(rule.HeadName, rule.OriginalTerms+1, rule)
+	}
+	for _, rule := range m.ConcatenateRules {
+		head(rule.HeadName, len(rule.TermConcatenation), concatenateRuleContext{rule})
+	}
+
+	body := func(name string, d int, rule fmt.Stringer) {
+		old, ok := dim[name]
+		if !ok {
+			vs.add(Pos{}, rule, false, "%s in body has no defining head", name)
+		} else if d >= 0 && old != d {
+			vs.add(Pos{}, rule, false, "%s in body has dimension %d, but the head used %d", name, d, old)
+		}
+	}
+	for _, rule := range m.PrependRules {
+		if rule.PrependIdx < 0 || rule.PrependIdx >= rule.Terms {
+			vs.add(Pos{}, nil, false, "%s :- %s: prepend index %d out of range", rule.HeadName, rule.BodyName, rule.PrependIdx)
+			continue
+		}
+		body(rule.BodyName, rule.Terms, rule)
+	}
+	for _, rule := range m.AppendRules {
+		if rule.AppendIdx < 0 || rule.AppendIdx >= rule.Terms {
+			vs.add(Pos{}, nil, false, "%s :- %s: append index %d out of range", rule.HeadName, rule.BodyName, rule.AppendIdx)
+			continue
+		}
+		body(rule.BodyName, rule.Terms, rule)
+	}
+	for _, rule := range m.InsertRules {
+		if rule.InsertIdx < 0 || rule.InsertIdx > rule.OriginalTerms || rule.OriginalTerms < 1 {
+			vs.add(Pos{}, nil, false, "%s :- %s: insert index %d out of range", rule.HeadName, rule.BodyName, rule.InsertIdx)
+			continue
+		}
+		body(rule.BodyName, rule.OriginalTerms, rule)
+	}
+	for _, rule := range m.ConcatenateRules {
+		context := concatenateRuleContext{rule}
+		if !context.wellFormed() {
+			vs.add(Pos{}, context, false, "head refers to a variable that does not occur in the body")
+			continue
+		}
+		seen := map[TermIdentifier]bool{}
+		for _, term := range rule.TermConcatenation {
+			if len(term) == 0 {
+				vs.add(Pos{}, context, false, "head contains an empty argument")
+			}
+			for _, id := range term {
+				if seen[id] {
+					vs.add(Pos{}, context, false, "head is non-linear, %s occurs twice", id)
+				}
+				seen[id] = true
+			}
+		}
+		for bodyIdx, name := range rule.BodyNames {
+			body(name, -1, context)
+			for _, term := range rule.TermConcatenation {
+				for _, id := range term {
+					if id.FromBodyIdx == bodyIdx && dim[name] > 0 && id.FromIndexInBody >= dim[name] {
+						vs.add(Pos{}, context, false, "%s refers to argument %d of %s of dimension %d", id, id.FromIndexInBody, name, dim[name])
+					}
+				}
+			}
+		}
+	}
+
+	checkStart(&vs, dim, Pos{}, nil)
+
+	return vs
+}
+
+// Validate returns a ValidationError if m has at least one error.
+func (m MCFG) Validate() error {
+	return validationError(m.Check())
+}
+
+// concatenateRuleContext prints concatenate rules that may refer to
+// variables missing from the body
+type concatenateRuleContext struct {
+	rule ConcatenateRule
+}
+
+func (c concatenateRuleContext) wellFormed() bool {
+	for _, term := range c.rule.TermConcatenation {
+		for _, id := range term {
+			if id.FromBodyIdx < 0 || id.FromBodyIdx >= len(c.rule.BodyNames) || id.FromBodyIdx >= len(_termLabels) || id.FromIndexInBody < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c concatenateRuleContext) String() string {
+	if c.wellFormed() {
+		return c.rule.String()
+	}
+	return fmt.Sprintf("%s(...) :- %s(...).", c.rule.HeadName, strings.Join(c.rule.BodyNames, "(...), "))
+}
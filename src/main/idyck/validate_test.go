@@ -0,0 +1,117 @@
+package idyck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGrammarCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar string
+		want    Violation
+	}{
+		{
+			"head dimension",
+			"S(X) :- A(X).\nA(a).\nA(b, c).",
+			Violation{Pos{3, 1}, "A(b, c).", "head A cannot have dimension 1 and 2", false},
+		},
+		{
+			"body dimension",
+			"S(X) :- A(X, Y).\nA(a, b).\nB(X) :- A(X).",
+			Violation{Pos{3, 9}, "B(X) :- A(X).", "A in body has dimension 1, but the head used 2", false},
+		},
+		{
+			"undefined body",
+			"S(X) :- B(X).",
+			Violation{Pos{1, 9}, "S(X) :- B(X).", "B in body has no defining head", false},
+		},
+		{
+			"free variable",
+			"S(X Y) :- A(X).\nA(a).",
+			Violation{Pos{1, 5}, "S(X Y) :- A(X).", "head contains free variable Y", false},
+		},
+		{
+			"duplicated head variable",
+			"S(X X) :- A(X).\nA(a).",
+			Violation{Pos{1, 5}, "S(X X) :- A(X).", "head is non-linear, X occurs twice", false},
+		},
+		{
+			"duplicated body variable",
+			"S(X) :- A(X, X).\nA(a, b).",
+			Violation{Pos{1, 14}, "S(X) :- A(X, X).", "body is non-linear, X occurs twice", false},
+		},
+		{
+			"terminal in body",
+			"S(X) :- A(a).\nA(a).",
+			Violation{Pos{1, 11}, "S(X) :- A(a).", "body contains non-variable a (all variables must be capitalized)", false},
+		},
+		{
+			"unused variable",
+			"S(X) :- A(X, Y).\nA(a, b).",
+			Violation{Pos{1, 14}, "S(X) :- A(X, Y).", "rule is deleting, Y does not occur in the head", true},
+		},
+		{
+			"permuting rule",
+			"S(X Y) :- A(Y, X).\nA(a, b).",
+			Violation{Pos{1, 11}, "S(X Y) :- A(Y, X).", "rule is permuting", true},
+		},
+		{
+			"start dimension",
+			"A(a, b).\nS(X, Y) :- A(X, Y).",
+			Violation{Pos{2, 1}, "S(X, Y) :- A(X, Y).", `Start symbol "S" has arity 2, not 1`, false},
+		},
+		{
+			"no start",
+			"A(a).",
+			Violation{Pos{}, "", "There should be a start symbol 'S'", false},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseMCFG(strings.NewReader(test.grammar))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ast.Check()
+			found := false
+			for _, v := range got {
+				found = found || v == test.want
+			}
+			if !found {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			if err := ast.Validate(); (err != nil) == test.want.Warning {
+				t.Errorf("Validate() = %v", err)
+			}
+		})
+	}
+}
+
+func TestMCFGCheck(t *testing.T) {
+	//a body index that no variable of the body has
+	m := MCFG{
+		BasicRules: []BasicRule{{HeadName: "A", Label: "a"}},
+		ConcatenateRules: []ConcatenateRule{{
+			HeadName:          "S",
+			BodyNames:         []string{"A"},
+			TermConcatenation: []TermConcatenator{{{FromBodyIdx: -1, FromIndexInBody: 0}}},
+		}},
+	}
+	want := Violation{Pos{}, "S(...) :- A(...).", "head refers to a variable that does not occur in the body", false}
+	got := m.Check()
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if m.Validate() == nil {
+		t.Error("Validate() = nil")
+	}
+	if s := m.String(); !strings.Contains(s, want.Rule) {
+		t.Errorf("String() = %q", s)
+	}
+
+	m.ConcatenateRules[0].TermConcatenation[0][0].FromBodyIdx = 0
+	if err := m.Validate(); err != nil {
+		t.Error(err)
+	}
+}
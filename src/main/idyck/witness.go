@@ -0,0 +1,125 @@
+package idyck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoWitness is returned by Witness for a pair that the
+// under-approximation does not prove reachable.
+var ErrNoWitness = errors.New("pair not proven reachable by the under-approximation")
+
+// ruleKind is the kind of rule that derived a derivation
+type ruleKind uint8
+
+const (
+	basicKind ruleKind = iota
+	prependKind
+	appendKind
+	insertKind
+	concatKind
+)
+
+// justification is the first rule application that derived a derivation.
+// Its bodies were derived before it, so following justifications from any
+// derivation ends at basic rules.
+type justification struct {
+	kind   ruleKind
+	edge   Edge         //read by basic, prepend, append and insert rules
+	idx    int          //segment of the prepend, append or insert
+	rule   fmt.Stringer //the rule applied, a *concatRule for concatenations, nil for basic rules
+	bodies []derivationKey
+}
+
+// witnesses maps the derivations of a reach to their justification
+type witnesses map[derivationKey]justification
+
+// witnessPath returns the edges of a path from p.Start to p.End whose
+// word is derived from the start nonterminal, false if S(p) was not
+// derived. The epsilon self-loops are left out.
+func (r *reach) witnessPath(p Path) ([]Edge, bool) {
+	key := r.segmentsKey(_startID, segments{p})
+	if _, ok := r.witness[key]; !ok {
+		return nil, false
+	}
+	edges := []Edge{}
+	for _, edge := range r.witnessSegments(key)[0] {
+		if edge.Label != _epsilonLabel {
+			edges = append(edges, edge)
+		}
+	}
+	return edges, true
+}
+
+// witnessSegments returns the edges of every segment of the derivation key
+func (r *reach) witnessSegments(key derivationKey) [][]Edge {
+	j := r.witness[key]
+	if j.kind == basicKind {
+		return [][]Edge{{j.edge}}
+	}
+	if j.kind == concatKind {
+		bodies := make([][][]Edge, len(j.bodies))
+		for i, body := range j.bodies {
+			bodies[i] = r.witnessSegments(body)
+		}
+		res := [][]Edge{}
+		for _, term := range j.rule.(*concatRule).TermConcatenation {
+			segment := []Edge{}
+			for _, t := range term {
+				segment = append(segment, bodies[t.FromBodyIdx][t.FromIndexInBody]...)
+			}
+			res = append(res, segment)
+		}
+		return res
+	}
+
+	body := r.witnessSegments(j.bodies[0])
+	switch j.kind {
+	case prependKind:
+		body[j.idx] = append([]Edge{j.edge}, body[j.idx]...)
+	case appendKind:
+		body[j.idx] = append(body[j.idx], j.edge)
+	case insertKind:
+		body = append(body[:j.idx], append([][]Edge{{j.edge}}, body[j.idx:]...)...)
+	}
+	return body
+}
+
+// Witness returns the edges of a path from pair.Start to pair.End whose
+// label word is balanced for the interleaved Dyck language, as derived by
+// the under-approximation (see UnderApprox). ErrNoWitness is returned if
+// the under-approximation does not contain pair.
+func (a *Analyzer) Witness(ctx context.Context, g *Graph, pair Path) ([]Edge, error) {
+	_, _, gCopy := parseDyckComponentNaive(g)
+
+	target := pair
+	if a.valueflow() {
+		gCopy = gCopy.valueflowTransformation()
+		target = makePath(3*pair.Start, 3*pair.End+2)
+	}
+
+	for _, gComp := range gCopy.splitComponents() {
+		if !gComp.vertices[target.Start] {
+			continue
+		}
+		parList, braList, comp := ParseDyckComponent(gComp)
+		grammar, _ := InterleavedDyckGrammar(parList, braList)
+		reachData := newReach(comp, compileGrammar(&grammar), false)
+		reachData.witness = witnesses{}
+		if _, err := reachData.run(ctx); err != nil {
+			return nil, err
+		}
+		edges, ok := reachData.witnessPath(target)
+		if !ok {
+			break
+		}
+		if a.valueflow() {
+			for i, edge := range edges {
+				edges[i] = Edge{From: edge.From / 3, To: edge.To / 3, Label: edge.Label}
+			}
+		}
+		return edges, nil
+	}
+	return nil, ErrNoWitness
+}
@@ -0,0 +1,83 @@
+package idyck
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// accepts reports whether grammar derives the word of edges, by running
+// it on a path graph with the labels of edges
+func accepts(t *testing.T, grammar *CompiledGrammar, edges []Edge) bool {
+	line := MakeGraph()
+	line.addVertex(0)
+	for i, e := range edges {
+		line.AddEdge(Vertex(i), Vertex(i+1), e.Label)
+	}
+	paths, err := ReachCompiled(context.Background(), line, grammar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return containsPath(paths, makePath(0, Vertex(len(edges))))
+}
+
+// isPath reports whether edges are edges of g that go from pair.Start to
+// pair.End in order
+func isPath(g *Graph, edges []Edge, pair Path) bool {
+	at := pair.Start
+	for _, e := range edges {
+		if e.From != at || !hasVertex(g.OutEdges(e.From, e.Label), e.To) {
+			return false
+		}
+		at = e.To
+	}
+	return at == pair.End
+}
+
+// TestWitness checks that the witness of every pair of the
+// under-approximation is a path of the graph with a word of the
+// interleaved Dyck grammar. For valueflow graphs the witness is found on
+// the transformed graph and its edges are mapped back to edges of g.
+func TestWitness(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	for _, kind := range []Kind{Taint, Valueflow} {
+		witnesses := 0
+		for i := 0; i < 20; i++ {
+			g := randomGraph(rng, 8, 16, _dyckTestLabels)
+			grammars, _ := dyckGrammars(t, g)
+			grammar, err := CompileGrammar(grammars["interleaved"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := NewAnalyzer(Config{Parallelism: 1, Kind: kind})
+			res, err := a.RunContext(ctx, g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, pair := range res.Underapproximation {
+				edges, err := a.Witness(ctx, g, pair)
+				if err != nil {
+					t.Fatalf("%s graph %d: no witness for %v: %v", kind, i, pair, err)
+				}
+				if !isPath(g, edges, pair) {
+					t.Errorf("%s graph %d: witness %v of %v is not a path of the graph", kind, i, edges, pair)
+				}
+				if !accepts(t, grammar, edges) {
+					t.Errorf("%s graph %d: the word of witness %v of %v is not derived", kind, i, edges, pair)
+				}
+				witnesses++
+			}
+			for _, v := range g.Vertices() {
+				pair := makePath(v, v+100)
+				if _, err := a.Witness(ctx, g, pair); !errors.Is(err, ErrNoWitness) {
+					t.Errorf("%s graph %d: witness of %v: %v", kind, i, pair, err)
+				}
+			}
+		}
+		if witnesses == 0 {
+			t.Errorf("%s: no pairs in the under-approximations", kind)
+		}
+	}
+}
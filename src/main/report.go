@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"src/main/main/idyck"
+)
+
+// report is the machine readable result of the run command
+type report struct {
+	Input    inputReport   `json:"input"`
+	Config   configReport  `json:"config"`
+	Stages   []stageReport `json:"stages"`
+	Seconds  float64       `json:"seconds"`
+	Memory   memReport     `json:"memory"`
+	Finished time.Time     `json:"finished"`
+	//stage that was running when the run was cancelled, empty if it finished
+	Interrupted idyck.Stage `json:"interrupted,omitempty"`
+}
+
+type memReport struct {
+	Allocs        uint64 `json:"allocs"`
+	AllocBytes    uint64 `json:"alloc_bytes"`
+	PeakHeapBytes uint64 `json:"peak_heap_bytes"`
+}
+
+// one all-pairs reachability computation
+type reachReport struct {
+	Vertices     int     `json:"vertices"`
+	Edges        int     `json:"edges"`
+	Pairs        int     `json:"pairs"`
+	Derivations  int     `json:"derivations"`
+	PeakWorklist int     `json:"peak_worklist"`
+	Seconds      float64 `json:"seconds"`
+}
+
+type inputReport struct {
+	Graph     string `json:"graph"`
+	Benchmark string `json:"benchmark"`
+	Bytes     int64  `json:"bytes"`
+	Vertices  int    `json:"vertices"`
+	Edges     int    `json:"edges"`
+}
+
+type configReport struct {
+	Kind     string        `json:"kind"`
+	ParityK  int           `json:"k"`
+	Stages   []idyck.Stage `json:"stages"`
+	Prune    []idyck.Stage `json:"prune"`
+	Workers  int           `json:"parallel"`
+	BottomUp bool          `json:"bottom_up"`
+}
+
+type stageReport struct {
+	Stage          idyck.Stage `json:"stage"`
+	Title          string      `json:"title"`
+	Pairs          int         `json:"pairs"`
+	Pruned         bool        `json:"pruned"`
+	Partial        bool        `json:"partial"`
+	VerticesBefore int         `json:"vertices_before"`
+	EdgesBefore    int         `json:"edges_before"`
+	VerticesAfter  int         `json:"vertices_after"`
+	EdgesAfter     int         `json:"edges_after"`
+	Seconds        float64     `json:"seconds"`
+
+	ReachCalls   int           `json:"reach_calls"`
+	ReachSeconds float64       `json:"reach_seconds"`
+	Derivations  int           `json:"derivations"`
+	PeakWorklist int           `json:"peak_worklist"`
+	Memory       memReport     `json:"memory"`
+	Reach        []reachReport `json:"reach"`
+}
+
+func makeMemReport(m idyck.MemStats) memReport {
+	return memReport{Allocs: m.Allocs, AllocBytes: m.AllocBytes, PeakHeapBytes: m.PeakHeap}
+}
+
+func makeReport(graphFile string, config idyck.Config, res idyck.Result) report {
+	r := report{
+		Input: inputReport{
+			Graph:     graphFile,
+			Benchmark: benchmarkName(graphFile),
+			Vertices:  res.Vertices,
+			Edges:     res.Edges,
+		},
+		Config: configReport{
+			Kind:     config.Kind.String(),
+			ParityK:  config.ParityK,
+			Stages:   config.Stages,
+			Prune:    config.Prune,
+			Workers:  config.Parallelism,
+			BottomUp: config.BottomUp,
+		},
+		Stages:   []stageReport{},
+		Seconds:  res.Duration.Seconds(),
+		Memory:   makeMemReport(res.Memory),
+		Finished: time.Now().UTC(),
+
+		Interrupted: res.Interrupted,
+	}
+	if info, err := os.Stat(graphFile); err == nil {
+		r.Input.Bytes = info.Size()
+	}
+	for _, stats := range res.Stats {
+		reach := []reachReport{}
+		for _, call := range stats.Reach {
+			reach = append(reach, reachReport{
+				Vertices:     call.Vertices,
+				Edges:        call.Edges,
+				Pairs:        call.Pairs,
+				Derivations:  call.Derivations,
+				PeakWorklist: call.PeakWorklist,
+				Seconds:      call.Duration.Seconds(),
+			})
+		}
+		r.Stages = append(r.Stages, stageReport{
+			Stage:          stats.Stage,
+			Title:          stats.Stage.Title(),
+			Pairs:          stats.Pairs,
+			Pruned:         stats.Pruned,
+			Partial:        stats.Partial,
+			VerticesBefore: stats.VerticesBefore,
+			EdgesBefore:    stats.EdgesBefore,
+			VerticesAfter:  stats.VerticesAfter,
+			EdgesAfter:     stats.EdgesAfter,
+			Seconds:        stats.Duration.Seconds(),
+			ReachCalls:     len(stats.Reach),
+			ReachSeconds:   stats.ReachDuration().Seconds(),
+			Derivations:    stats.Derivations(),
+			PeakWorklist:   stats.PeakWorklist(),
+			Memory:         makeMemReport(stats.Memory),
+			Reach:          reach,
+		})
+	}
+	return r
+}
+
+// benchmarkName is the file name of the graph without its extension
+func benchmarkName(graphFile string) string {
+	name := filepath.Base(graphFile)
+	return name[:len(name)-len(filepath.Ext(name))]
+}
+
+func (r report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+var csvHeader = []string{"benchmark", "graph", "kind", "k", "vertices", "edges", "stage", "pairs", "pruned",
+	"vertices_before", "edges_before", "vertices_after", "edges_after", "seconds",
+	"reach_calls", "reach_seconds", "derivations", "peak_worklist", "allocs", "alloc_bytes", "peak_heap_bytes", "partial"}
+
+// writeCSV writes one row per stage, with the input and configuration
+// repeated in every row and the reachability computations summed up
+func (r report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, s := range r.Stages {
+		cw.Write([]string{
+			r.Input.Benchmark,
+			r.Input.Graph,
+			r.Config.Kind,
+			strconv.Itoa(r.Config.ParityK),
+			strconv.Itoa(r.Input.Vertices),
+			strconv.Itoa(r.Input.Edges),
+			string(s.Stage),
+			strconv.Itoa(s.Pairs),
+			strconv.FormatBool(s.Pruned),
+			strconv.Itoa(s.VerticesBefore),
+			strconv.Itoa(s.EdgesBefore),
+			strconv.Itoa(s.VerticesAfter),
+			strconv.Itoa(s.EdgesAfter),
+			strconv.FormatFloat(s.Seconds, 'f', 3, 64),
+			strconv.Itoa(s.ReachCalls),
+			strconv.FormatFloat(s.ReachSeconds, 'f', 3, 64),
+			strconv.Itoa(s.Derivations),
+			strconv.Itoa(s.PeakWorklist),
+			strconv.FormatUint(s.Memory.Allocs, 10),
+			strconv.FormatUint(s.Memory.AllocBytes, 10),
+			strconv.FormatUint(s.Memory.PeakHeapBytes, 10),
+			strconv.FormatBool(s.Partial),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeReportFile writes r to name with writeTo, nothing if name is empty
+func writeReportFile(name string, writeTo func(io.Writer) error) error {
+	if name == "" {
+		return nil
+	}
+	out, err := createOutput(name)
+	if err != nil {
+		return err
+	}
+	if err := writeTo(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}